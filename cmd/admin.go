@@ -0,0 +1,76 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	log "github.com/cowdogmoo/guacinator/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// adminCmd represents the admin command
+	adminCmd = &cobra.Command{
+		Use:   "admin",
+		Short: "Administer the Guacamole instance.",
+	}
+
+	adminSetPasswordCmd = &cobra.Command{
+		Use:   "set-password",
+		Short: "Set a secure password for the guacadmin user.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			newPassword, err := requireSecret(cmd, "new-password", "new guacadmin password", true)
+			if err != nil {
+				return err
+			}
+
+			oldPassword, err := requireSecret(cmd, "old-password", "current guacadmin password", false)
+			if err != nil {
+				return err
+			}
+
+			log.Info("Setting secure password for guacadmin")
+			if err := guacService.SetAdminPassword(cmd.Context(), oldPassword, newPassword); err != nil {
+				return fmt.Errorf("failed to set new Guacamole admin password: %w", err)
+			}
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	addGuacFlags(adminCmd)
+
+	adminCmd.AddCommand(adminSetPasswordCmd)
+
+	adminSetPasswordCmd.Flags().StringP(
+		"new-password", "", "", "New password for the guacadmin user (we should not leave it as guacadmin, env GUACINATOR_NEW_PASSWORD).")
+	addSecretFlags(adminSetPasswordCmd.Flags(), "new-password", "new password for the guacadmin user")
+	adminSetPasswordCmd.Flags().String(
+		"old-password", "", "Current password of the guacadmin user (env GUACINATOR_OLD_PASSWORD, prompted for on a terminal).")
+	addSecretFlags(adminSetPasswordCmd.Flags(), "old-password", "current password of the guacadmin user")
+}
@@ -0,0 +1,450 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/spf13/cobra"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+var (
+	manifestFile   string
+	pruneEnabled   bool
+	pruneGroups    []string
+	pruneSelectors map[string]string
+
+	// applyCmd represents the apply command
+	applyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update Guacamole objects to match a YAML manifest.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			desired, state, changes, err := planManifest(cmd.Context(), manifestFile)
+			if err != nil {
+				return err
+			}
+
+			if len(changes) == 0 {
+				fmt.Println("No changes, Guacamole matches the manifest.")
+				return nil
+			}
+
+			return applyChanges(cmd.Context(), desired, state, changes)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	addGuacFlags(applyCmd)
+
+	addManifestFlags(applyCmd)
+}
+
+// addManifestFlags registers the flags shared by
+// the commands comparing Guacamole with a manifest.
+func addManifestFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVarP(&manifestFile, "filename", "f", "", "Path to the manifest describing the desired state.")
+	f.BoolVar(&pruneEnabled, "prune", false, "Delete objects within the prune scope that are not in the manifest.")
+	f.StringSliceVar(&pruneGroups, "prune-group", nil,
+		"Connection group whose descendants may be pruned (repeatable, ROOT for all connections).")
+	f.StringToStringVar(&pruneSelectors, "prune-selector", nil,
+		"Attribute a user or user group must have to be pruned, e.g. guac-organizational-role=lab (repeatable).")
+
+	if err := cmd.MarkFlagRequired("filename"); err != nil {
+		cobra.CheckErr(err)
+	}
+}
+
+// pruneScope builds the scope of a prune from the
+// command line, refusing to prune without one.
+func pruneScope() (manifest.PruneScope, error) {
+	if len(pruneGroups) == 0 && len(pruneSelectors) == 0 {
+		return manifest.PruneScope{}, errors.New("refusing to prune without --prune-group or --prune-selector")
+	}
+
+	return manifest.PruneScope{
+		ConnectionGroups: pruneGroups,
+		Selector:         pruneSelectors,
+		// Never lock ourselves out.
+		Protected: []string{guacCfg.Username},
+	}, nil
+}
+
+// planManifest loads a manifest and computes the changes
+// needed to make Guacamole match it.
+func planManifest(ctx context.Context, path string) (*manifest.Manifest, *instanceState, []manifest.Change, error) {
+	desired, err := manifest.Load(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var scope manifest.PruneScope
+	if pruneEnabled {
+		if scope, err = pruneScope(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	state, err := fetchState(ctx, guacService)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read the current state of Guacamole: %w", err)
+	}
+
+	changes := manifest.Diff(desired, &state.Manifest)
+	if pruneEnabled {
+		changes = append(changes, manifest.Prune(desired, &state.Manifest, scope)...)
+	}
+
+	return desired, state, changes, nil
+}
+
+// applyChanges performs changes in order, printing each one
+// as it completes. When ctx is cancelled, the changes already
+// made are summarized and the rest are left for the next run.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the remaining changes.
+// desired: The manifest the changes come from.
+// state: The current state of the instance to change.
+// changes: The changes to perform.
+//
+// **Returns:**
+//
+// error: An error if a change fails or ctx is cancelled.
+func applyChanges(ctx context.Context, desired *manifest.Manifest, state *instanceState, changes []manifest.Change) error {
+	for i, change := range changes {
+		err := ctx.Err()
+		if err == nil {
+			err = applyChange(ctx, desired, state, change)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted after %d of %d changes, run again to finish: %w", i, len(changes), ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+		fmt.Printf("%sd %s %s\n", change.Action, change.Kind, change.Name)
+	}
+
+	return nil
+}
+
+// applyChange performs a single change computed by manifest.Diff,
+// recording the identifiers of created objects in the state.
+func applyChange(ctx context.Context, desired *manifest.Manifest, state *instanceState, change manifest.Change) error {
+	if change.Action == manifest.ActionDelete {
+		return deleteObject(ctx, state, change)
+	}
+
+	switch change.Kind {
+	case manifest.KindConnectionGroup:
+		g, _ := desired.FindConnectionGroup(change.Name)
+		return applyConnectionGroup(ctx, g, state, change.Action)
+	case manifest.KindConnection:
+		c, _ := desired.FindConnection(change.Name)
+		return applyConnection(ctx, c, state, change.Action)
+	case manifest.KindSharingProfile:
+		p, _ := desired.FindSharingProfile(change.Name)
+		return applySharingProfile(ctx, p, state, change.Action)
+	case manifest.KindUserGroup:
+		g, _ := desired.FindUserGroup(change.Name)
+		return applyUserGroup(ctx, g, state, change.Action)
+	case manifest.KindUser:
+		u, _ := desired.FindUser(change.Name)
+		return applyUser(ctx, u, state, change.Action)
+	default:
+		return fmt.Errorf("unknown kind %q", change.Kind)
+	}
+}
+
+// deleteObject removes an object pruned from Guacamole.
+func deleteObject(ctx context.Context, state *instanceState, change manifest.Change) error {
+	switch change.Kind {
+	case manifest.KindConnectionGroup:
+		return state.client.DeleteConnectionGroup(ctx, state.groupIDs[change.Name])
+	case manifest.KindConnection:
+		return state.client.DeleteConnection(ctx, state.connIDs[change.Name])
+	case manifest.KindSharingProfile:
+		return state.client.DeleteSharingProfile(ctx, state.sharingIDs[change.Name])
+	case manifest.KindUserGroup:
+		return state.client.DeleteUserGroup(ctx, change.Name)
+	case manifest.KindUser:
+		return state.client.DeleteGuacUser(ctx, change.Name)
+	default:
+		return fmt.Errorf("unknown kind %q", change.Kind)
+	}
+}
+
+func applyConnectionGroup(ctx context.Context, desired manifest.ConnectionGroup, state *instanceState, action manifest.Action) error {
+	if current, found := state.FindConnectionGroup(desired.Path()); found {
+		desired.Attributes = manifest.MergeAttributes(current.Attributes, desired.Attributes)
+	}
+
+	group, err := desired.GuacConnectionGroup()
+	if err != nil {
+		return err
+	}
+	if group.ParentIdentifier, err = state.groupID(desired.Parent); err != nil {
+		return err
+	}
+
+	if action == manifest.ActionCreate {
+		if err := state.client.CreateConnectionGroup(ctx, &group); err != nil {
+			return err
+		}
+		state.groupIDs[desired.Path()] = group.Identifier
+		return nil
+	}
+
+	group.Identifier = state.groupIDs[desired.Path()]
+
+	return state.client.UpdateConnectionGroup(ctx, &group)
+}
+
+func applyConnection(ctx context.Context, desired manifest.Connection, state *instanceState, action manifest.Action) error {
+	if current, found := state.FindConnection(desired.Path()); found {
+		desired.Parameters = manifest.KeepSecrets(current.Parameters, desired.Parameters)
+		desired.Attributes = manifest.MergeAttributes(current.Attributes, desired.Attributes)
+	}
+
+	conn, err := desired.GuacConnection()
+	if err != nil {
+		return err
+	}
+	if conn.ParentIdentifier, err = state.groupID(desired.Group); err != nil {
+		return err
+	}
+
+	if action == manifest.ActionCreate {
+		if err := state.client.CreateConnection(ctx, &conn); err != nil {
+			return err
+		}
+		state.connIDs[desired.Path()] = conn.Identifier
+		return nil
+	}
+
+	conn.Identifier = state.connIDs[desired.Path()]
+
+	return state.client.UpdateConnection(ctx, &conn)
+}
+
+func applySharingProfile(ctx context.Context, desired manifest.SharingProfile, state *instanceState, action manifest.Action) error {
+	if current, found := state.FindSharingProfile(desired.Path()); found {
+		desired.Parameters = manifest.KeepSecrets(current.Parameters, desired.Parameters)
+		desired.Attributes = manifest.MergeAttributes(current.Attributes, desired.Attributes)
+	}
+
+	connID, err := state.connID(desired.Connection)
+	if err != nil {
+		return err
+	}
+	profile := guacamole.SharingProfile{
+		Name:                        desired.Name,
+		PrimaryConnectionIdentifier: connID,
+		Parameters:                  desired.Parameters,
+		Attributes:                  desired.Attributes,
+	}
+
+	if action == manifest.ActionCreate {
+		if err := state.client.CreateSharingProfile(ctx, &profile); err != nil {
+			return err
+		}
+		state.sharingIDs[desired.Path()] = profile.Identifier
+		return nil
+	}
+
+	profile.Identifier = state.sharingIDs[desired.Path()]
+
+	return state.client.UpdateSharingProfile(ctx, &profile)
+}
+
+func applyUserGroup(ctx context.Context, desired manifest.UserGroup, state *instanceState, action manifest.Action) error {
+	current, _ := state.FindUserGroup(desired.Name)
+	desired.Attributes = manifest.MergeAttributes(current.Attributes, desired.Attributes)
+
+	group, err := desired.GuacUserGroup()
+	if err != nil {
+		return err
+	}
+
+	if action == manifest.ActionCreate {
+		err = state.client.CreateUserGroup(ctx, &group)
+	} else {
+		err = state.client.UpdateUserGroup(ctx, &group)
+	}
+	if err != nil {
+		return err
+	}
+
+	items, err := permissionPatch(current.Permissions, desired.Permissions, state)
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	return state.client.SetUserGroupPermissions(ctx, desired.Name, items)
+}
+
+func applyUser(ctx context.Context, desired manifest.User, state *instanceState, action manifest.Action) error {
+	current, _ := state.FindUser(desired.Username)
+	desired.Attributes = manifest.MergeAttributes(current.Attributes, desired.Attributes)
+
+	u, err := desired.GuacUser()
+	if err != nil {
+		return err
+	}
+
+	if action == manifest.ActionCreate {
+		err = state.client.CreateGuacUser(ctx, &u)
+	} else {
+		// Passwords are only set when the user is created.
+		u.Password = ""
+		err = state.client.UpdateGuacUser(ctx, &u)
+	}
+	if err != nil {
+		return err
+	}
+
+	if desired.Groups != nil {
+		items := membershipPatch(current.Groups, desired.Groups)
+		if len(items) > 0 {
+			if err := state.client.SetUserGroupMembership(ctx, desired.Username, items); err != nil {
+				return err
+			}
+		}
+	}
+
+	items, err := permissionPatch(current.Permissions, desired.Permissions, state)
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	return state.client.SetUserPermissions(ctx, desired.Username, items)
+}
+
+// membershipPatch builds the operations adding and
+// removing a user from user groups.
+func membershipPatch(current, desired []string) []types.GuacPermissionItem {
+	var items []types.GuacPermissionItem
+
+	added, removed := diffSets(current, desired)
+	for _, group := range added {
+		items = append(items, guacamole.GroupMembership(guacamole.OpAdd, group))
+	}
+	for _, group := range removed {
+		items = append(items, guacamole.GroupMembership(guacamole.OpRemove, group))
+	}
+
+	return items
+}
+
+// permissionPatch builds the operations granting and revoking
+// permissions, resolving connection and group paths to identifiers.
+func permissionPatch(current, desired *manifest.Permissions, state *instanceState) ([]types.GuacPermissionItem, error) {
+	if desired == nil {
+		return nil, nil
+	}
+	if current == nil {
+		current = &manifest.Permissions{}
+	}
+
+	var items []types.GuacPermissionItem
+
+	added, removed := diffSets(current.System, desired.System)
+	for _, perm := range added {
+		items = append(items, guacamole.SystemPermission(guacamole.OpAdd, perm))
+	}
+	for _, perm := range removed {
+		items = append(items, guacamole.SystemPermission(guacamole.OpRemove, perm))
+	}
+
+	added, removed = diffSets(current.Connections, desired.Connections)
+	for _, path := range added {
+		id, err := state.connID(path)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, guacamole.ConnectionPermission(guacamole.OpAdd, id))
+	}
+	for _, path := range removed {
+		items = append(items, guacamole.ConnectionPermission(guacamole.OpRemove, state.connIDs[path]))
+	}
+
+	added, removed = diffSets(current.ConnectionGroups, desired.ConnectionGroups)
+	for _, path := range added {
+		id, err := state.groupID(path)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, guacamole.ConnectionGroupPermission(guacamole.OpAdd, id))
+	}
+	for _, path := range removed {
+		id, _ := state.groupID(path)
+		items = append(items, guacamole.ConnectionGroupPermission(guacamole.OpRemove, id))
+	}
+
+	added, removed = diffSets(current.SharingProfiles, desired.SharingProfiles)
+	for _, path := range added {
+		id, err := state.sharingID(path)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, guacamole.SharingProfilePermission(guacamole.OpAdd, id))
+	}
+	for _, path := range removed {
+		items = append(items, guacamole.SharingProfilePermission(guacamole.OpRemove, state.sharingIDs[path]))
+	}
+
+	return items, nil
+}
+
+// diffSets returns the values only in desired
+// and the values only in current.
+func diffSets(current, desired []string) (added, removed []string) {
+	inCurrent := map[string]bool{}
+	for _, v := range current {
+		inCurrent[v] = true
+	}
+	inDesired := map[string]bool{}
+	for _, v := range desired {
+		inDesired[v] = true
+		if !inCurrent[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range current {
+		if !inDesired[v] {
+			removed = append(removed, v)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,257 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configForce       bool
+	configShowSecrets bool
+
+	// configCmd represents the config command
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the guacinator config file.",
+		Long: `Manage the guacinator config file.
+
+Every subcommand works on the config file in use, chosen with --config,
+GUACINATOR_CONFIG or the default locations. Changes keep the comments in
+the file.`,
+	}
+
+	configInitCmd = &cobra.Command{
+		Use:   "init [FILE]",
+		Short: "Write the default config file.",
+		Long: `Write the default config file to FILE, which defaults to the config
+file in use. An existing file is only overwritten with --force.`,
+		Args: cobra.MaximumNArgs(1),
+		// Writing the config must not wait for it to exist.
+		Annotations: map[string]string{skipConfigAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath(args)
+			if _, err := os.Stat(path); err == nil && !configForce {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+			}
+
+			if err := writeDefaultConfig(path); err != nil {
+				return err
+			}
+
+			fmt.Printf("Wrote the default config to %s\n", path)
+
+			return nil
+		},
+	}
+
+	configViewCmd = &cobra.Command{
+		Use:   "view",
+		Short: "Show the config file with its secrets masked.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(guacConfigFile)
+			if err != nil {
+				return fmt.Errorf("failed to read config %s: %w", guacConfigFile, err)
+			}
+
+			if !configShowSecrets {
+				if data, err = config.MaskSecrets(data); err != nil {
+					return fmt.Errorf("failed to parse config %s: %w", guacConfigFile, err)
+				}
+			}
+
+			_, err = os.Stdout.Write(data)
+
+			return err
+		},
+	}
+
+	configGetCmd = &cobra.Command{
+		Use:   "get KEY",
+		Short: "Show the value in effect for a key, such as guac.scheme.",
+		Long: `Show the value in effect for a key, such as guac.scheme. The value
+comes from the environment when set there, otherwise from the config file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value := viper.Get(args[0])
+			if value == nil {
+				return fmt.Errorf("%s is not set", args[0])
+			}
+
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				data, err := yaml.Marshal(value)
+				if err != nil {
+					return err
+				}
+				fmt.Print(string(data))
+			default:
+				fmt.Println(value)
+			}
+
+			return nil
+		},
+	}
+
+	configSetCmd = &cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Set a key, such as guac.scheme, in the config file.",
+		Long: `Set a key, such as guac.scheme, in the config file. The file is left
+unchanged when the new value is invalid.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setConfigKey(guacConfigFile, args[0], args[1])
+		},
+	}
+
+	configEditCmd = &cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in $VISUAL or $EDITOR.",
+		Long: `Open the config file in $VISUAL or $EDITOR, falling back to vi, and
+check it for problems once the editor exits.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			editor := strings.Fields(firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR")))
+			if len(editor) == 0 {
+				editor = []string{"vi"}
+			}
+			edit := exec.Command(editor[0], append(editor[1:], guacConfigFile)...)
+			edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := edit.Run(); err != nil {
+				return fmt.Errorf("failed to run %s: %w", editor[0], err)
+			}
+
+			problems, err := config.ValidateFile(guacConfigFile)
+			if err != nil {
+				return err
+			}
+
+			return reportProblems(guacConfigFile, problems)
+		},
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [FILE]",
+		Short: "Check the config file for problems.",
+		Long: `Check the config file for problems, reporting every one found along
+with its line in the file. FILE defaults to the config file in use.`,
+		Args: cobra.MaximumNArgs(1),
+		// The config file is read here, so syntax errors are
+		// reported with their line rather than failing startup.
+		Annotations: map[string]string{skipConfigAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath(args)
+			problems, err := config.ValidateFile(path)
+			if err != nil {
+				return err
+			}
+			if err := reportProblems(path, problems); err != nil {
+				return err
+			}
+
+			fmt.Printf("%s is valid\n", path)
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd, configViewCmd, configGetCmd,
+		configSetCmd, configEditCmd, configValidateCmd)
+
+	configInitCmd.Flags().BoolVar(&configForce, "force", false, "Overwrite an existing config file.")
+	configViewCmd.Flags().BoolVar(&configShowSecrets, "show-secrets", false, "Show passwords, tokens and header values instead of masking them.")
+}
+
+// configPath returns the file named on the command
+// line, falling back to the config file in use.
+func configPath(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	return guacConfigFile
+}
+
+// setConfigKey sets a key in a config file, restoring
+// the file when the new value makes the key invalid.
+func setConfigKey(path, key, value string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	if err := config.SetKey(path, key, value); err != nil {
+		return err
+	}
+
+	problems, err := config.ValidateFile(path)
+	if err != nil {
+		return errors.Join(err, os.WriteFile(path, original, info.Mode().Perm()))
+	}
+
+	for _, p := range problems {
+		if p.Key == key || strings.HasPrefix(p.Key, key+".") || strings.HasPrefix(p.Key, key+"[") {
+			if err := os.WriteFile(path, original, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return fmt.Errorf("invalid value for %s: %s", key, p.Message)
+		}
+	}
+
+	return nil
+}
+
+// reportProblems prints the problems found in a config
+// file, returning an error when there are any.
+func reportProblems(path string, problems []config.Problem) error {
+	for _, p := range problems {
+		if p.Line > 0 {
+			fmt.Printf("%s:%d: %s\n", path, p.Line, p)
+		} else {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(problems), path)
+	}
+
+	return nil
+}
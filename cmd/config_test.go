@@ -0,0 +1,77 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	guacinator "github.com/cowdogmoo/guacinator/cmd"
+	"github.com/mitchellh/go-homedir"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigInit(t *testing.T) {
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name:     "Default config in an empty home",
+			expected: filepath.Join(".guacinator", "guacinator-config.yaml"),
+		},
+		{
+			name:     "New file given with --config",
+			config:   "new.yaml",
+			expected: "new.yaml",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			t.Setenv("GUACINATOR_CONFIG", "")
+
+			config := ""
+			if tc.config != "" {
+				config = filepath.Join(dir, tc.config)
+			}
+
+			guacinator.RootCmd.SetArgs([]string{"--config=" + config, "config", "init"})
+			require.NoError(t, guacinator.RootCmd.Execute())
+
+			data, err := os.ReadFile(filepath.Join(dir, tc.expected))
+			require.NoError(t, err)
+			require.Contains(t, string(data), "guac:")
+
+			guacinator.RootCmd.SetArgs([]string{"--config=" + config, "config", "init"})
+			require.ErrorContains(t, guacinator.RootCmd.Execute(), "already exists")
+		})
+	}
+}
+
+func TestConfigSetInvalidValue(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", fakeKubectl(t)+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(dir, "guacinator-config.yaml")
+	contents := "guac:\n  port: 443\n"
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	require.NoError(t, os.Chmod(path, 0644))
+
+	guacinator.RootCmd.SetArgs([]string{"--config", path, "config", "set", "guac.port", "70000"})
+	require.ErrorContains(t, guacinator.RootCmd.Execute(), "invalid value for guac.port")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, contents, string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
@@ -0,0 +1,361 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/spf13/cobra"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+var (
+	// connectionCmd represents the connection command
+	connectionCmd = &cobra.Command{
+		Use:   "connection",
+		Short: "Manage Guacamole connections.",
+	}
+
+	newHost           guacamole.Host
+	sshPrivateKeyFile string
+
+	connectionCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a VNC, RDP, SSH or Telnet connection in Guacamole.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("port") {
+				newHost.Port = defaultPort(newHost.Protocol)
+			}
+
+			if err := readHostSecrets(cmd); err != nil {
+				return err
+			}
+
+			if sshPrivateKeyFile != "" {
+				key, err := os.ReadFile(sshPrivateKeyFile)
+				if err != nil {
+					return fmt.Errorf("failed to read SSH private key %s: %w", sshPrivateKeyFile, err)
+				}
+				newHost.SSH.PrivateKey = string(key)
+			}
+
+			if err := guacService.CreateGuacamoleConnection(cmd.Context(), newHost); err != nil {
+				return fmt.Errorf("failed to create %s connection in Guacamole: %w", newHost.Name, err)
+			}
+
+			fmt.Println("Successfully created connection " + newHost.Name)
+
+			return nil
+		},
+	}
+
+	kubeHost       guacamole.Host
+	kubeconfigPath string
+	kubeContext    string
+
+	connectionCreateKubernetesCmd = &cobra.Command{
+		Use:   "create-kubernetes",
+		Short: "Create a kubernetes pod connection in Guacamole from a kubeconfig context.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := readKubeconfig(kubeconfigPath, kubeContext)
+			if err != nil {
+				return fmt.Errorf("failed to read kubeconfig: %w", err)
+			}
+
+			host, err := HostFromKubeconfig(raw)
+			if err != nil {
+				return err
+			}
+			host.Name = kubeHost.Name
+			host.Group = kubeHost.Group
+			host.Kubernetes.Namespace = kubeHost.Kubernetes.Namespace
+			host.Kubernetes.Pod = kubeHost.Kubernetes.Pod
+			host.Kubernetes.Container = kubeHost.Kubernetes.Container
+
+			if err := guacService.CreateGuacamoleConnection(cmd.Context(), host); err != nil {
+				return fmt.Errorf("failed to create %s connection in Guacamole: %w", host.Name, err)
+			}
+
+			fmt.Println("Successfully created connection " + host.Name)
+
+			return nil
+		},
+	}
+
+	importColumns map[string]string
+
+	connectionImportCmd = &cobra.Command{
+		Use:   "import FILE",
+		Short: "Create a connection in Guacamole for each row of a CSV file.",
+		Long: `Create a connection in Guacamole for each row of a CSV file.
+
+The first row names the columns: name, protocol, ip, port, username, password
+and group. Use --columns to map fields to differently named columns. The
+protocol defaults to vnc and the port to the protocol's default. Every row
+is attempted, and the command fails if any row could not be imported.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", args[0], err)
+			}
+			defer f.Close()
+
+			rows, err := ReadHostsCSV(f, importColumns)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", args[0], err)
+			}
+
+			return importHosts(cmd.Context(), rows)
+		},
+	}
+
+	connectionListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the connections in Guacamole.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conns, err := guacService.ListConnections(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to list Guacamole connections: %w", err)
+			}
+
+			rows := make([][]string, 0, len(conns))
+			for _, conn := range conns {
+				rows = append(rows, []string{conn.Identifier, conn.Name, conn.Protocol, conn.ParentIdentifier})
+			}
+
+			return printTable([]string{"IDENTIFIER", "NAME", "PROTOCOL", "PARENT"}, rows)
+		},
+	}
+
+	connectionGetCmd = &cobra.Command{
+		Use:   "get IDENTIFIER",
+		Short: "Show the details of a Guacamole connection.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conn, err := guacService.GetConnection(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("failed to get connection %s from Guacamole: %w", args[0], err)
+			}
+
+			return printTable([]string{"IDENTIFIER", "NAME", "PROTOCOL", "PARENT", "HOSTNAME", "PORT"},
+				[][]string{{
+					conn.Identifier, conn.Name, conn.Protocol, conn.ParentIdentifier,
+					conn.Parameters.Hostname, conn.Parameters.Port,
+				}})
+		},
+	}
+
+	connectionDeleteCmd = &cobra.Command{
+		Use:   "delete IDENTIFIER",
+		Short: "Delete a Guacamole connection.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := guacService.DeleteConnection(cmd.Context(), args[0]); err != nil {
+				return fmt.Errorf("failed to delete connection %s from Guacamole: %w", args[0], err)
+			}
+
+			fmt.Println("Successfully deleted connection " + args[0])
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(connectionCmd)
+	addGuacFlags(connectionCmd)
+
+	connectionCmd.AddCommand(connectionCreateCmd, connectionCreateKubernetesCmd,
+		connectionImportCmd, connectionListCmd, connectionGetCmd, connectionDeleteCmd)
+
+	f := connectionCreateCmd.Flags()
+	f.StringVarP(&newHost.Name, "name", "n", "", "Name of the new connection.")
+	f.StringVar(&newHost.Group, "group", "", "Path of the connection group to create the connection in (default is ROOT).")
+	f.StringVar(&newHost.Protocol, "protocol", guacamole.ProtocolVNC, "Protocol of the new connection (vnc, rdp, ssh or telnet).")
+	f.StringVar(&newHost.IP, "host", "", "Hostname or IP address of the host to connect to.")
+	f.IntVar(&newHost.Port, "port", 0, "Port to connect to (default is guac.vnc_port from the config for VNC, otherwise the protocol's standard port).")
+	f.StringVar(&newHost.Username, "host-username", "", "Username used to log in to the host (not used by VNC).")
+	f.StringVar(&newHost.Password, "host-password", "", "Password used to log in to the host (env GUACINATOR_HOST_PASSWORD).")
+	addSecretFlags(f, "host-password", "password used to log in to the host")
+
+	// RDP specific settings
+	f.StringVar(&newHost.RDP.Domain, "rdp-domain", "", "Domain used to authenticate with the RDP host.")
+	f.StringVar(&newHost.RDP.Security, "rdp-security", "", "RDP security mode (any, nla, rdp, tls or vmconnect).")
+	f.BoolVar(&newHost.RDP.IgnoreCert, "rdp-ignore-cert", false, "Ignore the certificate presented by the RDP host.")
+	f.StringVar(&newHost.RDP.ResizeMethod, "rdp-resize-method", "", "Method used to resize the RDP display (display-update or reconnect).")
+	f.BoolVar(&newHost.RDP.EnableDrive, "rdp-enable-drive", false, "Enable drive redirection.")
+	f.StringVar(&newHost.RDP.DriveName, "rdp-drive-name", "", "Name of the redirected drive.")
+	f.StringVar(&newHost.RDP.DrivePath, "rdp-drive-path", "", "Path on the guacd server backing the redirected drive.")
+	f.StringVar(&newHost.RDP.GatewayHostname, "rdp-gateway-host", "", "Hostname of the remote desktop gateway.")
+	f.IntVar(&newHost.RDP.GatewayPort, "rdp-gateway-port", 0, "Port of the remote desktop gateway.")
+	f.StringVar(&newHost.RDP.GatewayUsername, "rdp-gateway-username", "", "Username used to authenticate with the remote desktop gateway.")
+	f.StringVar(&newHost.RDP.GatewayPassword, "rdp-gateway-password", "", "Password used to authenticate with the remote desktop gateway (env GUACINATOR_RDP_GATEWAY_PASSWORD).")
+	addSecretFlags(f, "rdp-gateway-password", "password used to authenticate with the remote desktop gateway")
+	f.StringVar(&newHost.RDP.GatewayDomain, "rdp-gateway-domain", "", "Domain used to authenticate with the remote desktop gateway.")
+
+	// SSH specific settings
+	f.StringVar(&sshPrivateKeyFile, "ssh-private-key-file", "", "Path to the private key used to authenticate with the SSH host.")
+	f.StringVar(&newHost.SSH.Passphrase, "ssh-passphrase", "", "Passphrase protecting the SSH private key (env GUACINATOR_SSH_PASSPHRASE).")
+	addSecretFlags(f, "ssh-passphrase", "passphrase protecting the SSH private key")
+	f.StringVar(&newHost.SSH.HostKey, "ssh-host-key", "", "Known public host key of the SSH host.")
+	f.BoolVar(&newHost.SSH.EnableSFTP, "ssh-enable-sftp", false, "Enable file transfer over SFTP.")
+
+	// Terminal settings shared by the text based protocols
+	f.StringVar(&newHost.Terminal.ColorScheme, "color-scheme", "", "Terminal color scheme (black-white, gray-black, green-black or white-black).")
+	f.StringVar(&newHost.Terminal.FontName, "font-name", "", "Name of the font used by the terminal.")
+	f.IntVar(&newHost.Terminal.FontSize, "font-size", 0, "Size of the font used by the terminal, in points.")
+
+	for _, flag := range []string{"name", "host"} {
+		if err := connectionCreateCmd.MarkFlagRequired(flag); err != nil {
+			cobra.CheckErr(err)
+		}
+	}
+
+	kf := connectionCreateKubernetesCmd.Flags()
+	kf.StringVarP(&kubeHost.Name, "name", "n", "", "Name of the new connection.")
+	kf.StringVar(&kubeHost.Group, "group", "", "Path of the connection group to create the connection in (default is ROOT).")
+	kf.StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file (default is kubectl's default).")
+	kf.StringVar(&kubeContext, "kube-context", "", "Kubeconfig context to read (default is the current context).")
+	kf.StringVar(&kubeHost.Kubernetes.Namespace, "namespace", "default", "Namespace of the pod.")
+	kf.StringVar(&kubeHost.Kubernetes.Pod, "pod", "", "Name of the pod to attach to.")
+	kf.StringVar(&kubeHost.Kubernetes.Container, "container", "", "Container within the pod (default is the first container).")
+
+	for _, flag := range []string{"name", "pod"} {
+		if err := connectionCreateKubernetesCmd.MarkFlagRequired(flag); err != nil {
+			cobra.CheckErr(err)
+		}
+	}
+
+	connectionImportCmd.Flags().StringToStringVar(&importColumns, "columns", nil,
+		"Map a field to a differently named CSV column, e.g. ip=Address,name=Hostname.")
+}
+
+// readHostSecrets reads the secrets of the new connection from
+// their flags, files, stdin or environment, prompting for the
+// password of VNC connections, which cannot do without one.
+func readHostSecrets(cmd *cobra.Command) error {
+	var err error
+	if newHost.Protocol == guacamole.ProtocolVNC {
+		newHost.Password, err = requireSecret(cmd, "host-password", "VNC password", false)
+	} else {
+		newHost.Password, err = readSecret(cmd, "host-password")
+	}
+	if err != nil {
+		return err
+	}
+
+	if newHost.RDP.GatewayPassword, err = readSecret(cmd, "rdp-gateway-password"); err != nil {
+		return err
+	}
+
+	newHost.SSH.Passphrase, err = readSecret(cmd, "ssh-passphrase")
+
+	return err
+}
+
+// importHosts creates a connection for each valid row, reporting
+// the outcome of every row and continuing past failures. When ctx
+// is cancelled, the rows imported so far are summarized.
+func importHosts(ctx context.Context, rows []HostRow) error {
+	groupIDs, err := importGroupIDs(ctx, rows)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			fmt.Printf("Imported %d of %d connections before being interrupted.\n", i-failed, len(rows))
+			return ctx.Err()
+		}
+
+		err := row.Err
+		if err == nil {
+			err = createImportedHost(ctx, row.Host, groupIDs)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("line %d (%s): failed: %v\n", row.Line, row.Host.Name, strings.ReplaceAll(err.Error(), "\n", "; "))
+			continue
+		}
+
+		fmt.Println("Successfully created connection " + row.Host.Name)
+	}
+
+	fmt.Printf("Imported %d of %d connections.\n", len(rows)-failed, len(rows))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(rows))
+	}
+
+	return nil
+}
+
+// importGroupIDs resolves the connection groups of the rows
+// to import, reading the connection tree once for all of them
+// rather than once per row. Only ROOT is known when no row
+// names a group.
+func importGroupIDs(ctx context.Context, rows []HostRow) (map[string]string, error) {
+	ids := guacamole.ConnectionGroupIDs(types.GuacConnectionGroup{})
+	for _, row := range rows {
+		if _, ok := ids[row.Host.Group]; row.Err == nil && !ok {
+			tree, err := guacService.GetConnectionTree(ctx, guacamole.RootGroup)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get connection tree: %w", err)
+			}
+			return guacamole.ConnectionGroupIDs(tree), nil
+		}
+	}
+
+	return ids, nil
+}
+
+// createImportedHost creates the connection of an
+// imported row in its group, resolved with groupIDs.
+func createImportedHost(ctx context.Context, host guacamole.Host, groupIDs map[string]string) error {
+	parent, ok := groupIDs[host.Group]
+	if !ok {
+		return fmt.Errorf("connection group %s does not exist", host.Group)
+	}
+
+	conn := host.GuacConnection(parent)
+
+	return guacService.CreateConnection(ctx, &conn)
+}
+
+// defaultPort returns the port a connection uses
+// when one is not explicitly provided.
+func defaultPort(protocol string) int {
+	if protocol == guacamole.ProtocolVNC {
+		return cfg.Guac.VNCPort
+	}
+
+	return guacamole.DefaultPort(protocol)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// contextCmd represents the context command
+	contextCmd = &cobra.Command{
+		Use:   "context",
+		Short: "Manage the Guacamole instances guacinator connects to.",
+		Long: `Manage the Guacamole instances guacinator connects to.
+
+Contexts are listed under contexts in the config file. Each holds the URL,
+username, data source and TLS settings of an instance, along with where to
+find the password. Commands use the context selected with --context or
+GUACINATOR_CONTEXT, otherwise current_context from the config file. Flags
+and environment variables override the values of the context.`,
+	}
+
+	contextListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the configured contexts.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadContexts()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tURL\tUSERNAME\tDATA SOURCE")
+			for _, ctx := range cfg.Contexts {
+				current := ""
+				if ctx.Name == cfg.CurrentContext {
+					current = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, ctx.Name, ctx.URL, ctx.Username, ctx.DataSource)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	contextCurrentCmd = &cobra.Command{
+		Use:   "current",
+		Short: "Show the current context.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadContexts()
+			if err != nil {
+				return err
+			}
+			if cfg.CurrentContext == "" {
+				return errors.New("current_context is not set")
+			}
+
+			fmt.Println(cfg.CurrentContext)
+
+			return nil
+		},
+	}
+
+	contextUseCmd = &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current context in the config file.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadContexts()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.FindContext(args[0]); !ok {
+				return fmt.Errorf("context %s is not configured under contexts in %s",
+					args[0], viper.ConfigFileUsed())
+			}
+
+			if err := config.SetKey(viper.ConfigFileUsed(), "current_context", args[0]); err != nil {
+				return err
+			}
+
+			fmt.Printf("Switched to context %s\n", args[0])
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd, contextCurrentCmd, contextUseCmd)
+}
+
+// loadContexts reads the contexts from the config file.
+//
+// **Returns:**
+//
+// config.Config: The config holding the contexts and the current context.
+//
+// error: An error if the contexts cannot be decoded.
+func loadContexts() (config.Config, error) {
+	cfg := config.Config{CurrentContext: viper.GetString("current_context")}
+	if err := viper.UnmarshalKey("contexts", &cfg.Contexts); err != nil {
+		return cfg, fmt.Errorf("failed to read contexts from %s: %w", viper.ConfigFileUsed(), err)
+	}
+
+	return cfg, nil
+}
+
+// findContext looks up a context by name in the config file.
+//
+// **Parameters:**
+//
+// name: The name of the context.
+//
+// **Returns:**
+//
+// config.Context: The context.
+//
+// error: An error if the context is not configured.
+func findContext(name string) (config.Context, error) {
+	cfg, err := loadContexts()
+	if err != nil {
+		return config.Context{}, err
+	}
+
+	ctx, ok := cfg.FindContext(name)
+	if !ok {
+		return ctx, fmt.Errorf("context %s is not configured under contexts in %s",
+			name, viper.ConfigFileUsed())
+	}
+
+	return ctx, nil
+}
+
+// activeContext returns the context selected with --context or
+// GUACINATOR_CONTEXT, falling back to current_context. A zero
+// context is returned when none is selected.
+func activeContext(cmd *cobra.Command) (config.Context, error) {
+	name := flagOrEnv(cmd, "context")
+	if name == "" {
+		name = viper.GetString("current_context")
+	}
+	if name == "" {
+		return config.Context{}, nil
+	}
+
+	return findContext(name)
+}
+
+// contextPassword reads the password a context refers to.
+//
+// **Parameters:**
+//
+// ctx: The context.
+//
+// **Returns:**
+//
+// string: The password, or an empty string if the context has none.
+//
+// error: An error if the password file cannot be read.
+func contextPassword(ctx config.Context) (string, error) {
+	if ctx.PasswordFile != "" {
+		path, err := homedir.Expand(ctx.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password of context %s: %w", ctx.Name, err)
+		}
+
+		return trimNewline(string(data)), nil
+	}
+
+	if ctx.PasswordEnv != "" {
+		return os.Getenv(ctx.PasswordEnv), nil
+	}
+
+	return "", nil
+}
+
+// contextGuacConfig generates the Guacamole client config of a
+// context, reaching its instance as the context describes.
+//
+// **Parameters:**
+//
+// ctx: The context.
+//
+// **Returns:**
+//
+// guacamole.Config: The client config of the context.
+//
+// error: An error if the context has no URL, its password cannot
+// be read or its TLS settings are invalid.
+func contextGuacConfig(ctx config.Context) (guacamole.Config, error) {
+	if strings.TrimSpace(ctx.URL) == "" {
+		return guacamole.Config{}, fmt.Errorf("context %s has no url", ctx.Name)
+	}
+
+	password, err := contextPassword(ctx)
+	if err != nil {
+		return guacamole.Config{}, err
+	}
+
+	basePath, settings := defaultSettings(ctx)
+	s, host := splitScheme(ctx.URL)
+	cfg, err := newGuacConfig(s, withBasePath(host, basePath), ctx.Username, password, settings)
+	cfg.DataSource = ctx.DataSource
+
+	return cfg, err
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	exportFormat      string
+	exportFile        string
+	exportOmitSecrets bool
+
+	// exportCmd represents the export command
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the objects in Guacamole as a manifest.",
+		Long: `Export every connection group, connection, sharing profile, user group
+and user visible to the authenticated user, along with their permissions.
+
+The output is a manifest that apply accepts, which makes it suitable for
+backups and for bringing a hand-built instance under declarative management.
+User passwords cannot be read back from Guacamole and are never exported.
+
+Manifests describe system permissions and READ permissions on connections,
+connection groups and sharing profiles. Other permissions, such as UPDATE or
+ADMINISTER on an object and permissions on users or user groups, are left
+out with a warning on stderr naming them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := fetchState(cmd.Context(), guacService)
+			if err != nil {
+				return fmt.Errorf("failed to read the current state of Guacamole: %w", err)
+			}
+
+			state.warnDropped(os.Stderr)
+
+			m := state.Manifest
+			if exportOmitSecrets {
+				m.OmitSecrets()
+			}
+
+			data, err := encodeManifest(&m, exportFormat)
+			if err != nil {
+				return err
+			}
+
+			if exportFile == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+
+			// The export may hold connection secrets.
+			if err := os.WriteFile(exportFile, data, 0600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", exportFile, err)
+			}
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	addGuacFlags(exportCmd)
+
+	f := exportCmd.Flags()
+	f.StringVarP(&exportFormat, "output", "o", "yaml", "Output format (yaml or json).")
+	f.StringVar(&exportFile, "file", "", "File to write the export to (default is stdout).")
+	f.BoolVar(&exportOmitSecrets, "omit-secrets", false, "Leave passwords, passphrases and private keys out of the export.")
+}
+
+// encodeManifest renders a manifest in the requested format.
+//
+// **Parameters:**
+//
+// m: The manifest to render.
+// format: The output format, yaml or json.
+//
+// **Returns:**
+//
+// []byte: The encoded manifest.
+//
+// error: An error if the format is unsupported or encoding fails.
+func encodeManifest(m *manifest.Manifest, format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return yaml.Marshal(m)
+	case "json":
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, must be yaml or json", format)
+	}
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import "github.com/cowdogmoo/guacinator/pkg/guacamole"
+
+// RootCmd exposes the root command to the tests of package cmd_test.
+var RootCmd = rootCmd
+
+// ImportHosts exposes importHosts to the tests of package cmd_test.
+var ImportHosts = importHosts
+
+// SetGuacService sets the client the commands use.
+func SetGuacService(client guacamole.GuacService) {
+	guacService = client
+}
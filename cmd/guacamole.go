@@ -23,434 +23,247 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
-	"strconv"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
 	log "github.com/cowdogmoo/guacinator/pkg/logging"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	guac "github.com/techBeck03/guacamole-api-client"
-	"github.com/techBeck03/guacamole-api-client/types"
 )
 
-// VncHost represents the parameters used to establish a new connection in Guacamole.
-//
-// **Attributes:**
-// Name:     A string representing the name of the VNC host.
-// IP:       A string representing the IP address of the VNC host.
-// Port:     An integer representing the port to connect to on the VNC host.
-// Password: A string representing the password for the VNC host.
-type VncHost struct {
-	Name     string
-	IP       string
-	Port     int
-	Password string
-}
-
-// GuacService represents the interface for interacting with the Guacamole API.
-//
-// **Methods:**
-// CreateGuacamoleConnection: Creates a new Guacamole connection.
-// CreateAdminUser:           Creates a new Guacamole admin user.
-// DeleteGuacUser:            Deletes a Guacamole user.
-type GuacService interface {
-	CreateGuacamoleConnection(vnchost VncHost) error
-	CreateAdminUser(user, password string) error
-	DeleteGuacUser(user string) error
-}
-
-// GuacServiceImpl represents the implementation of the GuacService interface.
-type GuacServiceImpl struct{}
-
 var (
-	guacCfg     guac.Config
-	guacClient  guac.Client
-	guacAdminPW string
-	user        string
-	password    string
-	vncHost     VncHost
-	scheme      string
-	guacURL     string
-
-	// guacamoleCmd represents the guacamole command
-	guacamoleCmd = &cobra.Command{
-		Use:   "guacamole",
-		Short: "Deploy and interface with Apache Guacamole.",
-
-		Run: func(cmd *cobra.Command, args []string) {
-			var err error
-			/* Retrieve CLI args */
-			guacURL, err = cmd.Flags().GetString("url")
-			if err != nil {
-				log.Error(
-					"Failed to get Guacamole URL from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-			user, err = cmd.Flags().GetString("username")
-			if err != nil {
-				log.Error(
-					"Failed to get username from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-			password, err = cmd.Flags().GetString("password")
-			if err != nil {
-				log.Error(
-					"Failed to get password from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-
-			/* Unmarshal viper values */
-			scheme = viper.GetString("guac.scheme")
-			guacURL = viper.GetString("guac.url")
-			vncHost.Port = viper.GetInt("guac.vnc_port")
-
-			// Generate Guacamole connection config
-			guacCfg = guac.Config{
-				URL:                    fmt.Sprintf("%s://%s", scheme, guacURL),
-				Username:               user,
-				Password:               password,
-				DisableTLSVerification: true,
-			}
-			guacService := &GuacServiceImpl{}
-
-			/* Functionality provided by this cobra command. */
-
-			// Get new guacadmin password from CLI (if applicable).
-			guacAdminPW, err = cmd.Flags().GetString("guacadmin-pw")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-
-			// Establish a client with Guacamole
-			if err := connectGuac(guacCfg); err != nil {
-				log.Error(err)
-				cobra.CheckErr(err)
-			}
-
-			if guacAdminPW != "" {
-				token, err := getToken()
-				if err != nil {
-					log.Error(err)
-					cobra.CheckErr(err)
-				}
-
-				log.Info("Setting secure password for guacadmin")
-				if err := setAdminPW(token, password, guacAdminPW); err != nil {
-					log.Error(
-						"Failed to set new Guacamole admin password: %v\n", err)
-					cobra.CheckErr(err)
-				}
-				os.Exit(0)
-			}
-
-			vncHost.Name, err = cmd.Flags().GetString("connection")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-
-			vncHost.Password, err = cmd.Flags().GetString("vnc-pw")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-
-			vncHost.IP, err = cmd.Flags().GetString("vnc-ip")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-
-			if vncHost.Name != "" && vncHost.Password != "" && vncHost.IP != "" {
-				if err := guacService.CreateGuacamoleConnection(vncHost); err != nil {
-					log.Error(
-						"Failed to create %s connection in Guacamole: %v", vncHost.Name, err)
-					cobra.CheckErr(err)
-				}
-				os.Exit(0)
-			} else if vncHost.Name != "" && vncHost.Password == "" || vncHost.IP == "" {
-				log.Error(
-					"You must provide all required information to " +
-						"add a new connection in Guacamole")
-				cobra.CheckErr(err)
-			}
-
-			delUser, err := cmd.Flags().GetString("delete-user")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-			if delUser != "" {
-				if err := guacService.DeleteGuacUser(delUser); err != nil {
-					log.Error(
-						"Failed to delete %s from Guacamole: %v", delUser, err)
-					cobra.CheckErr(err)
-				}
-				os.Exit(0)
-			}
-
-			newAdmin, err := cmd.Flags().GetString("new-admin")
-			if err != nil {
-				log.Error(
-					"Failed to get input from CLI input: %v", err)
-				cobra.CheckErr(err)
-			}
-			if newAdmin != "" {
-				if err := guacService.CreateAdminUser(newAdmin, password); err != nil {
-					log.Error(
-						"Failed to create %s admin in Guacamole: %v", newAdmin, err)
-					cobra.CheckErr(err)
-				}
-				os.Exit(0)
-			}
-		},
-	}
+	// guacCfg describes the instance the command connects to.
+	guacCfg guacamole.Config
+	// guacService is the client the command operates through,
+	// authenticated by setupGuacClient.
+	guacService guacamole.GuacService
 )
 
-func init() {
-	rootCmd.AddCommand(guacamoleCmd)
+// addGuacFlags registers the flags used to authenticate with
+// Guacamole on a resource command and connects to Guacamole before
+// any of its subcommands run.
+func addGuacFlags(cmd *cobra.Command) {
+	addConnectionFlags(cmd)
 
-	// Required inputs
-	guacamoleCmd.Flags().StringP(
-		"url", "l", "", "Guacamole URL.")
-	if err := guacamoleCmd.MarkFlagRequired("url"); err != nil {
-		log.Error(
-			"Failed to mark required flag url: %v", err)
-		cobra.CheckErr(err)
-	}
-	guacamoleCmd.Flags().StringP(
-		"username", "u", "", "Username used to authenticate with Guacamole.")
-	if err := guacamoleCmd.MarkFlagRequired("username"); err != nil {
-		log.Error(
-			"Failed to mark required flag username: %v", err)
-		cobra.CheckErr(err)
-	}
-	guacamoleCmd.Flags().StringP(
-		"password", "p", "", "Password used to authenticate with Guacamole.")
-	if err := guacamoleCmd.MarkFlagRequired("password"); err != nil {
-		log.Error(
-			"Failed to mark required flag password: %v", err)
-		cobra.CheckErr(err)
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return setupGuacClient(cmd)
 	}
+}
 
-	// Functionality provided by this cobra command.
-	guacamoleCmd.Flags().StringP(
-		"guacadmin-pw", "", "", "New password for the guacadmin user (we should not leave it as guacadmin).")
-	guacamoleCmd.Flags().StringP(
-		"connection", "", "", "Create a connection in Guacamole.")
-	guacamoleCmd.Flags().StringP(
-		"vnc-pw", "", "", "VNC password for device. Required to create a new connection.")
-	guacamoleCmd.Flags().StringP(
-		"vnc-ip", "", "", "IP address of host running VNC. Required to create a new connection.")
-	guacamoleCmd.Flags().StringP(
-		"delete-user", "", "", "Delete an input Guacamole user.")
-	guacamoleCmd.Flags().StringP(
-		"new-admin", "", "", "Create a new Guacamole admin user.")
+// addConnectionFlags registers the flags describing
+// the Guacamole instance to connect to and the user
+// to authenticate as. Each can also be provided
+// through a GUACINATOR_* environment variable.
+func addConnectionFlags(cmd *cobra.Command) {
+	pf := cmd.PersistentFlags()
+	pf.StringP("url", "l", "", "Guacamole URL (env GUACINATOR_URL).")
+	pf.StringP("username", "u", "", "Username used to authenticate with Guacamole (env GUACINATOR_USERNAME).")
+	pf.StringP("password", "p", "", "Password used to authenticate with Guacamole (env GUACINATOR_PASSWORD).")
+	addSecretFlags(pf, "password", "password used to authenticate with Guacamole")
+	addTransportFlags(pf)
+	pf.String("context", "", "Name of the context in the config file to connect with (env GUACINATOR_CONTEXT, default is current_context).")
+	pf.String("data-source", "", "Guacamole data source to operate on, such as postgresql, mysql or ldap (default is guac.data_source from the config, otherwise the data source Guacamole authenticated against).")
 }
 
-// CreateGuacamoleConnection establishes a new connection
-//
-// in Guacamole using the provided VncHost information.
-// CreatePackageDocs generates documentation for all Go packages in the current
-// directory and its subdirectories. It traverses the file tree using a provided
-// afero.Fs and Repo to create a new README.md file in each directory containing
-// a Go package. It uses a specified template file for generating the README files.
-//
-// **Parameters:**
-//
-// vncHost: A VncHost struct containing the necessary information for the connection.
-//
-// **Returns:**
-//
-// error: An error if the connection cannot be created.
-func (g *GuacServiceImpl) CreateGuacamoleConnection(vncHost VncHost) error {
-	newConnection := types.GuacConnection{
-		Name:             vncHost.Name,
-		ParentIdentifier: "ROOT",
-		Protocol:         "vnc",
-		Attributes: types.GuacConnectionAttributes{
-			MaxConnections:        "2",
-			MaxConnectionsPerUser: "1",
-		},
-		Parameters: types.GuacConnectionParameters{
-			Hostname: vncHost.IP,
-			Port:     strconv.Itoa(vncHost.Port),
-			Password: vncHost.Password,
-		},
+// setupGuacClient reads the Guacamole connection details from the
+// command line and the config file and establishes a client.
+func setupGuacClient(cmd *cobra.Command) error {
+	if err := readGuacFlags(cmd); err != nil {
+		return err
 	}
 
-	if err := guacClient.CreateConnection(&newConnection); err != nil {
-		log.Error(
-			"Failed to create %s connection in Guacamole: %v", vncHost.Name, err)
+	if err := waitForGuac(cmd, guacCfg); err != nil {
 		return err
 	}
 
+	client, err := connectGuac(cmd.Context(), guacCfg)
+	if err != nil {
+		return err
+	}
+	guacService = client
+
 	return nil
 }
 
-// CreateAdminUser creates a new admin user in
-// Guacamole with the specified
-// username and password.
-//
-// **Parameters:**
-//
-// user: A string representing the desired username for the new admin user.
-//
-// password: A string representing the desired password for the new admin user.
-//
-// **Returns:**
-//
-// error: An error if the admin user cannot be created.
-func (g *GuacServiceImpl) CreateAdminUser(user, password string) error {
-	newUser := types.GuacUser{
-		Username: user,
-		Password: password,
+// readGuacFlags builds the Guacamole client config from the
+// command line, the environment, the selected context and the
+// config file. The password may be left empty while a cached
+// token is valid.
+func readGuacFlags(cmd *cobra.Command) error {
+	ctx, err := activeContext(cmd)
+	if err != nil {
+		return err
 	}
 
-	if err := guacClient.CreateUser(&newUser); err != nil {
+	// guac.port only applies to the URL of the guac section.
+	guacURL, port := firstNonEmpty(flagOrEnv(cmd, "url"), ctx.URL), 0
+	if guacURL == "" {
+		guacURL, port = cfg.Guac.URL, cfg.Guac.Port
+	}
+	if guacURL == "" {
+		return errors.New("a Guacamole URL is required: use --url, GUACINATOR_URL, a context or guac.url")
+	}
+	user := firstNonEmpty(flagOrEnv(cmd, "username"), ctx.Username)
+	if user == "" {
+		return errors.New("a Guacamole username is required: use --username, GUACINATOR_USERNAME or a context")
+	}
+	password, err := readSecret(cmd, "password")
+	if err != nil {
 		return err
 	}
-
-	permissionItems := []types.GuacPermissionItem{
-		guacClient.NewAddSystemPermission(types.SystemPermissions{}.Administer()),
-		guacClient.NewAddSystemPermission(types.SystemPermissions{}.CreateUser()),
-		guacClient.NewAddSystemPermission(types.SystemPermissions{}.CreateConnection()),
-		guacClient.NewAddSystemPermission(types.SystemPermissions{}.CreateConnectionGroup()),
-		guacClient.NewAddSystemPermission(types.SystemPermissions{}.CreateSharingProfile()),
+	if password == "" {
+		if password, err = contextPassword(ctx); err != nil {
+			return err
+		}
 	}
 
-	if err := guacClient.SetUserPermissions(newUser.Username, &permissionItems); err != nil {
+	basePath, settings := defaultSettings(ctx)
+	if settings, err = readTransportFlags(cmd, settings); err != nil {
 		return err
 	}
 
-	fmt.Println("Successfully created admin user " + newUser.Username)
-
-	return nil
-}
-
-// DeleteGuacUser removes a specified Guacamole user.
-//
-// **Parameters:**
-//
-// user: A string representing the username of the Guacamole user to be deleted.
-//
-// **Returns:**
-//
-// error: An error if the specified user cannot be deleted.
-func (g *GuacServiceImpl) DeleteGuacUser(user string) error {
-	if err := guacClient.DeleteUser(user); err != nil {
+	scheme, host := splitScheme(guacURL)
+	host = withBasePath(withPort(host, port), firstNonEmpty(flagOrEnv(cmd, "base-path"), basePath))
+	if guacCfg, err = newGuacConfig(scheme, host, user, password, settings); err != nil {
 		return err
 	}
 
-	fmt.Println("Successfully created " + user)
+	guacCfg.DataSource = firstNonEmpty(ctx.DataSource, cfg.Guac.DataSource)
+	if cmd.Flags().Changed("data-source") {
+		if guacCfg.DataSource, err = cmd.Flags().GetString("data-source"); err != nil {
+			return fmt.Errorf("failed to get data source from CLI input: %w", err)
+		}
+	}
 
 	return nil
-
 }
 
-func getToken() (string, error) {
-	var token string
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
 
-	tokenPath := "api/tokens"
-	resp, err := http.PostForm(fmt.Sprintf("%s://%s/%s", scheme, guacURL, tokenPath),
-		url.Values{
-			"username": []string{user},
-			"password": []string{password},
-		})
+	return ""
+}
 
-	if err != nil {
-		log.Error(
-			"Failed to get token from Guacamole: %v", err,
-		)
-		return token, err
+// splitScheme separates the scheme from a Guacamole URL. The scheme
+// may be provided as part of the URL, otherwise the configured
+// scheme is used.
+func splitScheme(rawURL string) (string, string) {
+	s := cfg.Guac.Scheme
+	if prefix, rest, found := strings.Cut(rawURL, "://"); found {
+		s, rawURL = prefix, rest
 	}
 
-	defer resp.Body.Close()
+	return s, strings.TrimSuffix(rawURL, "/")
+}
 
-	body, err := io.ReadAll(resp.Body)
+// newGuacConfig generates the Guacamole client config for an
+// instance, reached as described by settings.
+func newGuacConfig(scheme, host, username, password string, settings instanceSettings) (guacamole.Config, error) {
+	tlsCfg, err := settings.TLS.ClientConfig()
 	if err != nil {
-		log.Error(
-			"Failed to read response body from Guacamole: %v", err,
-		)
-		return token, err
-	}
-
-	var tokenresp types.AuthenticationResponse
-	if err := json.Unmarshal(body, &tokenresp); err != nil {
-		log.Error(
-			"Failed to unmarshal response body from Guacamole: %v", err,
-		)
-		return token, err
+		return guacamole.Config{}, fmt.Errorf("invalid TLS settings for %s: %w", host, err)
 	}
-	token = tokenresp.AuthToken
 
-	return token, nil
+	return guacamole.Config{
+		URL:       fmt.Sprintf("%s://%s", scheme, host),
+		Username:  username,
+		Password:  password,
+		TLS:       tlsCfg,
+		Proxy:     settings.Proxy,
+		Headers:   settings.Headers,
+		UserAgent: userAgent,
+		Retry:     guacamole.RetryPolicy(settings.Retry),
+		Timeout:   settings.Timeout,
+		Logf:      log.Debug,
+	}, nil
 }
 
-func connectGuac(cfg guac.Config) error {
-	guacClient = guac.New(cfg)
-
-	if err := guacClient.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to Guacamole: %v", err)
+// printTable writes the input rows to stdout as aligned columns.
+func printTable(headers []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
 
-	return nil
+	return w.Flush()
 }
 
-func setAdminPW(token string, old string, new string) error {
-	data := map[string]string{
-		"oldPassword": old,
-		"newPassword": new,
+// connectGuac establishes a session with Guacamole, reusing a
+// cached auth token while it is valid and otherwise logging in
+// and caching the new token.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the login.
+//
+// cfg: The config of the Guacamole instance to connect to. When
+// cfg.DataSource is empty, the data source Guacamole authenticated
+// against is used.
+//
+// **Returns:**
+//
+// *guacamole.Client: The authenticated client.
+//
+// error: An error if authentication or connecting fails.
+func connectGuac(ctx context.Context, cfg guacamole.Config) (*guacamole.Client, error) {
+	cache := loadTokenCache()
+	key := tokenCacheKey(cfg)
+
+	if cached, ok := cache[key]; ok {
+		client, err := guacamole.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		err = client.Resume(ctx, cached.authResponse())
+		if err == nil {
+			log.Debug("Reusing cached token for %s", key)
+			return client, nil
+		}
+		log.Debug("Cached token for %s is no longer valid: %v", key, err)
 	}
 
-	payload, err := json.Marshal(data)
-	if err != nil {
-		log.Error(
-			"Failed to marshal payload for Guacamole: %v", err,
-		)
-		return err
+	return login(ctx, cfg, cache)
+}
+
+// login authenticates with Guacamole using the credentials
+// in cfg and caches the new token.
+func login(ctx context.Context, cfg guacamole.Config, cache tokenCache) (*guacamole.Client, error) {
+	if cfg.Password == "" {
+		if !canPrompt() {
+			return nil, missingSecret("password", "Guacamole password")
+		}
+
+		var err error
+		if cfg.Password, err = promptSecret(fmt.Sprintf("password for %s", tokenCacheKey(cfg)), false); err != nil {
+			return nil, err
+		}
 	}
 
-	adminPWResetURL := fmt.Sprintf("%s://%s/api/session/data/postgresql/users/guacadmin/password", scheme, guacURL)
-	req, err := http.NewRequest("PUT", adminPWResetURL, bytes.NewBuffer(payload))
+	client, err := guacamole.New(cfg)
 	if err != nil {
-		log.Error(
-			"Failed to create request for Guacamole: %v", err,
-		)
-		return err
+		return nil, err
 	}
 
-	req.Header.Set("content-type", "application/json;charset=UTF-8")
-	req.Header.Set("guacamole-token", token)
-	req.Header.Set("user-agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36")
-
-	resp, err := http.DefaultClient.Do(req)
+	auth, err := client.Login(ctx)
 	if err != nil {
-		log.Error(
-			"Failed to send request to Guacamole: %v", err,
-		)
-		return err
+		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 204 {
-		log.Error(
-			"Failed to change password: %v", err,
-		)
+	cache[tokenCacheKey(cfg)] = newCachedToken(auth)
+	if err := cache.save(); err != nil {
+		log.Warn("Failed to cache the Guacamole token: %v", err)
 	}
 
-	return nil
+	return client, nil
 }
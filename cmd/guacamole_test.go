@@ -1,160 +1,90 @@
 package cmd_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	guacinator "github.com/cowdogmoo/guacinator/cmd"
-	"github.com/stretchr/testify/mock"
+	"github.com/mitchellh/go-homedir"
 	"github.com/stretchr/testify/require"
+	"github.com/techBeck03/guacamole-api-client/types"
 )
 
-type MockGuacService struct {
-	mock.Mock
-}
+func TestGuacURLFromConfig(t *testing.T) {
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
 
-var mockService = new(MockGuacService)
+	mux := newGuacMux()
+	mux.HandleFunc("GET /api/session/data/postgresql/users", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"guacadmin":{"username":"guacadmin"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
 
-func (m *MockGuacService) CreateGuacamoleConnection(vncHost guacinator.VncHost) error {
-	args := m.Called(vncHost)
-	return args.Error(0)
-}
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
 
-func (m *MockGuacService) CreateAdminUser(user, password string) error {
-	args := m.Called(user, password)
-	return args.Error(0)
-}
-
-func (m *MockGuacService) DeleteGuacUser(user string) error {
-	args := m.Called(user)
-	return args.Error(0)
-}
-
-func TestCreateGuacamoleConnection(t *testing.T) {
 	tests := []struct {
-		name      string
-		vncHost   guacinator.VncHost
-		expectErr bool
+		name   string
+		config string
 	}{
 		{
-			name: "Valid VncHost",
-			vncHost: guacinator.VncHost{
-				Name:     "Example",
-				IP:       "guacamole.techvomit.xyz",
-				Port:     5900,
-				Password: "guacadmin",
-			},
-			expectErr: false,
+			name:   "URL with a port",
+			config: fmt.Sprintf("guac:\n  url: %s\n", srv.URL),
 		},
 		{
-			name: "Invalid VncHost",
-			vncHost: guacinator.VncHost{
-				Name:     "Invalid",
-				IP:       "192.168.1.300", // Invalid IP
-				Port:     5900,
-				Password: "password",
-			},
-			expectErr: true,
+			name:   "URL without a port",
+			config: fmt.Sprintf("guac:\n  scheme: http\n  url: %s\n  port: %s\n", host, port),
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			if tc.expectErr {
-				mockService.On("CreateGuacamoleConnection", tc.vncHost).Return(fmt.Errorf("some error"))
-			} else {
-				mockService.On("CreateGuacamoleConnection", tc.vncHost).Return(nil)
-			}
-
-			err := mockService.CreateGuacamoleConnection(tc.vncHost)
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("GUACINATOR_URL", "")
+			t.Setenv("PATH", fakeKubectl(t)+string(os.PathListSeparator)+os.Getenv("PATH"))
 
-			mockService.AssertExpectations(t)
+			path := filepath.Join(dir, "guacinator-config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.config), 0600))
 
-			if tc.expectErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
+			guacinator.RootCmd.SetArgs([]string{"--config", path, "user", "list",
+				"--username", "guacadmin", "--password", "secret"})
+			require.NoError(t, guacinator.RootCmd.Execute())
 		})
 	}
 }
 
-func TestCreateAdminUser(t *testing.T) {
-	tests := []struct {
-		name      string
-		user      string
-		password  string
-		expectErr bool
-	}{
-		{
-			name:      "Valid user and password",
-			user:      "admin",
-			password:  "secure_password",
-			expectErr: false,
-		},
-		{
-			name:      "Invalid user",
-			user:      "", // Empty user
-			password:  "secure_password",
-			expectErr: true,
-		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			if tc.expectErr {
-				mockService.On("CreateAdminUser", tc.user, tc.password).Return(fmt.Errorf("some error"))
-			} else {
-				mockService.On("CreateAdminUser", tc.user, tc.password).Return(nil)
-			}
-
-			err := mockService.CreateAdminUser(tc.user, tc.password)
-
-			mockService.AssertExpectations(t)
-
-			if tc.expectErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
+// newGuacMux returns a mux serving the Guacamole login,
+// which hands out the token "token" for the postgresql
+// data source.
+func newGuacMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/tokens", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.AuthenticationResponse{
+			AuthToken:            "token",
+			Username:             r.FormValue("username"),
+			DataSource:           "postgresql",
+			AvailableDataSources: []string{"postgresql"},
 		})
-	}
-}
-func TestDeleteGuacUser(t *testing.T) {
-	tests := []struct {
-		name      string
-		user      string
-		expectErr bool
-	}{
-		{
-			name:      "Valid user",
-			user:      "user_to_delete",
-			expectErr: false,
-		},
-		{
-			name:      "Invalid user",
-			user:      "", // Empty user
-			expectErr: true,
-		},
-	}
+	})
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			if tc.expectErr {
-				mockService.On("DeleteGuacUser", tc.user).Return(fmt.Errorf("some error"))
-			} else {
-				mockService.On("DeleteGuacUser", tc.user).Return(nil)
-			}
+	return mux
+}
 
-			err := mockService.DeleteGuacUser(tc.user)
+// fakeKubectl returns a directory holding a kubectl
+// that does nothing, which the dependency check requires.
+func fakeKubectl(t *testing.T) string {
+	t.Helper()
 
-			mockService.AssertExpectations(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kubectl"), []byte("#!/bin/sh\n"), 0700))
 
-			if tc.expectErr {
-				require.Error(t, err)
-			} else {
-				require.NoError(t, err)
-			}
-		})
-	}
+	return dir
 }
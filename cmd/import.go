@@ -0,0 +1,172 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// csvFields are the Host fields that can be read from a CSV column.
+var csvFields = []string{"name", "protocol", "ip", "port", "username", "password", "group"}
+
+// HostRow is a single row of a CSV host import.
+//
+// **Attributes:**
+//
+// Line: The line of the row in the CSV file.
+// Host: The host described by the row.
+// Err:  An error if the row could not be parsed.
+type HostRow struct {
+	Line int
+	Host guacamole.Host
+	Err  error
+}
+
+// ReadHostsCSV parses a CSV file describing one host per row.
+// The first row is a header naming the columns. Columns are matched
+// to Host fields by name, unless columns maps a field to another header.
+//
+// **Parameters:**
+//
+// r: The CSV data.
+// columns: Header names keyed by field (name, protocol, ip, port,
+// username, password or group), for headers that differ from the field.
+//
+// **Returns:**
+//
+// []HostRow: The parsed rows, each with its own error if it is invalid.
+//
+// error: An error if the CSV or its header is invalid.
+func ReadHostsCSV(r io.Reader, columns map[string]string) ([]HostRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	index, err := columnIndex(header, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []HostRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return rows, err
+			}
+			rows = append(rows, HostRow{Line: parseErr.Line, Err: parseErr.Err})
+			continue
+		}
+
+		host, err := hostFromRecord(record, index)
+		rows = append(rows, HostRow{Line: line, Host: host, Err: err})
+	}
+}
+
+// columnIndex maps each Host field to the position of its column.
+func columnIndex(header []string, columns map[string]string) (map[string]int, error) {
+	for field := range columns {
+		if !types.StrSlice(csvFields).Has(field) {
+			return nil, fmt.Errorf("unknown field %q in column mapping, must be one of %v", field, csvFields)
+		}
+	}
+
+	positions := map[string]int{}
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	index := map[string]int{}
+	for _, field := range csvFields {
+		name := field
+		if mapped, ok := columns[field]; ok {
+			name = strings.ToLower(mapped)
+		}
+		if i, ok := positions[name]; ok {
+			index[field] = i
+		} else if _, mapped := columns[field]; mapped {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the CSV header", columns[field], field)
+		}
+	}
+
+	for _, field := range []string{"name", "ip"} {
+		if _, ok := index[field]; !ok {
+			return nil, fmt.Errorf("the CSV has no %s column", field)
+		}
+	}
+
+	return index, nil
+}
+
+// hostFromRecord builds a Host from a CSV record, defaulting
+// the protocol to VNC and the port to the protocol's default.
+func hostFromRecord(record []string, index map[string]int) (guacamole.Host, error) {
+	value := func(field string) string {
+		if i, ok := index[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	host := guacamole.Host{
+		Name:     value("name"),
+		Protocol: strings.ToLower(value("protocol")),
+		IP:       value("ip"),
+		Username: value("username"),
+		Password: value("password"),
+		Group:    value("group"),
+	}
+	if host.Protocol == "" {
+		host.Protocol = guacamole.ProtocolVNC
+	}
+
+	if port := value("port"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return host, fmt.Errorf("invalid port %q", port)
+		}
+		host.Port = p
+	} else {
+		host.Port = defaultPort(host.Protocol)
+	}
+
+	return host, host.Validate()
+}
@@ -0,0 +1,139 @@
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	guacinator "github.com/cowdogmoo/guacinator/cmd"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/stretchr/testify/require"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+func TestReadHostsCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		columns   map[string]string
+		expected  []guacinator.HostRow
+		expectErr bool
+	}{
+		{
+			name: "Default columns",
+			data: "name,protocol,ip,port,username,password,group\n" +
+				"web01,ssh,10.0.0.5,2222,admin,s3cret,lab/linux\n" +
+				"desk01,,10.0.0.6,5901,,vncpw,\n",
+			expected: []guacinator.HostRow{
+				{
+					Line: 2,
+					Host: guacamole.Host{
+						Name: "web01", Protocol: guacamole.ProtocolSSH, IP: "10.0.0.5", Port: 2222,
+						Username: "admin", Password: "s3cret", Group: "lab/linux",
+					},
+				},
+				{
+					Line: 3,
+					Host: guacamole.Host{
+						Name: "desk01", Protocol: guacamole.ProtocolVNC, IP: "10.0.0.6", Port: 5901,
+						Password: "vncpw",
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name:    "Mapped columns and default port",
+			data:    "Hostname,Address,Type\ndc01,10.0.0.10,RDP\n",
+			columns: map[string]string{"name": "Hostname", "ip": "Address", "protocol": "Type"},
+			expected: []guacinator.HostRow{
+				{
+					Line: 2,
+					Host: guacamole.Host{Name: "dc01", Protocol: guacamole.ProtocolRDP, IP: "10.0.0.10", Port: 3389},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name:      "Missing ip column",
+			data:      "name,port\nweb01,22\n",
+			expectErr: true,
+		},
+		{
+			name:      "Unknown mapped field",
+			data:      "name,ip\nweb01,10.0.0.5\n",
+			columns:   map[string]string{"hostname": "name"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, err := guacinator.ReadHostsCSV(strings.NewReader(tc.data), tc.columns)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, rows)
+			}
+		})
+	}
+}
+
+func TestReadHostsCSVInvalidRows(t *testing.T) {
+	data := "name,protocol,ip,port\n" +
+		"web01,ssh,10.0.0.5,twenty-two\n" +
+		"web02,gopher,10.0.0.6,70\n" +
+		"web03,ssh\n" +
+		"web04,ssh,10.0.0.8,22\n"
+
+	rows, err := guacinator.ReadHostsCSV(strings.NewReader(data), nil)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	for _, row := range rows[:3] {
+		require.Error(t, row.Err, "line %d", row.Line)
+	}
+	require.NoError(t, rows[3].Err)
+	require.Equal(t, 5, rows[3].Line)
+}
+
+func TestImportHosts(t *testing.T) {
+	trees := 0
+	var created []string
+	mux := newGuacMux()
+	mux.HandleFunc("GET /api/session/data/postgresql/connectionGroups/ROOT/tree", func(w http.ResponseWriter, r *http.Request) {
+		trees++
+		_, _ = w.Write([]byte(`{"identifier":"ROOT","childConnectionGroups":[` +
+			`{"identifier":"1","name":"lab","childConnectionGroups":[{"identifier":"2","name":"linux"}]}]}`))
+	})
+	mux.HandleFunc("POST /api/session/data/postgresql/connections", func(w http.ResponseWriter, r *http.Request) {
+		var conn types.GuacConnection
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&conn))
+		created = append(created, conn.ParentIdentifier+" "+conn.Name)
+		_ = json.NewEncoder(w).Encode(conn)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client, err := guacamole.New(guacamole.Config{URL: srv.URL, Username: "guacadmin", Password: "secret"})
+	require.NoError(t, err)
+	_, err = client.Login(context.Background())
+	require.NoError(t, err)
+	guacinator.SetGuacService(client)
+
+	rows, err := guacinator.ReadHostsCSV(strings.NewReader("name,protocol,ip,group\n"+
+		"web01,ssh,10.0.0.5,lab/linux\n"+
+		"web02,ssh,10.0.0.6,lab/linux\n"+
+		"web03,ssh,10.0.0.7,\n"+
+		"web04,ssh,10.0.0.8,lab/windows\n"), nil)
+	require.NoError(t, err)
+
+	err = guacinator.ImportHosts(context.Background(), rows)
+	require.EqualError(t, err, "1 of 4 rows failed")
+	require.Equal(t, 1, trees)
+	require.Equal(t, []string{"2 web01", "2 web02", "ROOT web03"}, created)
+}
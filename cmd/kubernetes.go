@@ -0,0 +1,142 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strconv"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+)
+
+// kubeconfig is the subset of a minified, flattened
+// kubeconfig needed to build a Guacamole kubernetes connection.
+type kubeconfig struct {
+	Clusters []struct {
+		Cluster struct {
+			Server                   string `json:"server"`
+			CertificateAuthorityData string `json:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		User struct {
+			ClientCertificateData string `json:"client-certificate-data"`
+			ClientKeyData         string `json:"client-key-data"`
+		} `json:"user"`
+	} `json:"users"`
+}
+
+// readKubeconfig uses kubectl to resolve a single kubeconfig
+// context, with any referenced certificate files inlined.
+func readKubeconfig(kubeconfigPath, context string) ([]byte, error) {
+	args := []string{"config", "view", "--raw", "--minify", "--flatten", "-o", "json"}
+	if kubeconfigPath != "" {
+		args = append(args, "--kubeconfig", kubeconfigPath)
+	}
+	if context != "" {
+		args = append(args, "--context", context)
+	}
+
+	out, err := exec.Command("kubectl", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("kubectl config view failed: %s", exitErr.Stderr)
+		}
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// HostFromKubeconfig builds a kubernetes Host from the
+// output of `kubectl config view --raw --minify --flatten -o json`.
+// The namespace, pod and container must be filled in by the caller.
+//
+// **Parameters:**
+//
+// raw: The minified kubeconfig, encoded as JSON.
+//
+// **Returns:**
+//
+// Host: A Host with the API server address and credentials of the context.
+//
+// error: An error if the kubeconfig cannot be parsed.
+func HostFromKubeconfig(raw []byte) (guacamole.Host, error) {
+	var kc kubeconfig
+	if err := json.Unmarshal(raw, &kc); err != nil {
+		return guacamole.Host{}, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	if len(kc.Clusters) != 1 || len(kc.Users) != 1 {
+		return guacamole.Host{}, errors.New("kubeconfig must contain exactly one cluster and user, use a minified kubeconfig")
+	}
+	cluster := kc.Clusters[0].Cluster
+	kubeUser := kc.Users[0].User
+
+	host := guacamole.Host{Protocol: guacamole.ProtocolKubernetes}
+
+	server, err := url.Parse(cluster.Server)
+	if err != nil || server.Host == "" {
+		return guacamole.Host{}, fmt.Errorf("invalid kubernetes API server %q", cluster.Server)
+	}
+	host.IP = server.Hostname()
+	host.Kubernetes.UseSSL = server.Scheme == "https"
+	host.Kubernetes.IgnoreCert = cluster.InsecureSkipTLSVerify
+	if port := server.Port(); port != "" {
+		if host.Port, err = strconv.Atoi(port); err != nil {
+			return guacamole.Host{}, fmt.Errorf("invalid kubernetes API server port %q", port)
+		}
+	} else if host.Kubernetes.UseSSL {
+		host.Port = 443
+	} else {
+		host.Port = 80
+	}
+
+	pems := []struct {
+		data string
+		dst  *string
+	}{
+		{cluster.CertificateAuthorityData, &host.Kubernetes.CACert},
+		{kubeUser.ClientCertificateData, &host.Kubernetes.ClientCert},
+		{kubeUser.ClientKeyData, &host.Kubernetes.ClientKey},
+	}
+	for _, pem := range pems {
+		if pem.data == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(pem.data)
+		if err != nil {
+			return guacamole.Host{}, fmt.Errorf("failed to decode kubeconfig certificate data: %w", err)
+		}
+		*pem.dst = string(decoded)
+	}
+
+	return host, nil
+}
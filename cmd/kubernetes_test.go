@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	guacinator "github.com/cowdogmoo/guacinator/cmd"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostFromKubeconfig(t *testing.T) {
+	b64 := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+		expected   guacamole.Host
+		expectErr  bool
+	}{
+		{
+			name: "Client certificate auth over TLS",
+			kubeconfig: fmt.Sprintf(`{
+				"clusters": [{"cluster": {"server": "https://10.0.0.1:6443", "certificate-authority-data": %q}}],
+				"users": [{"user": {"client-certificate-data": %q, "client-key-data": %q}}]
+			}`, b64("ca"), b64("cert"), b64("key")),
+			expected: guacamole.Host{
+				Protocol: guacamole.ProtocolKubernetes,
+				IP:       "10.0.0.1",
+				Port:     6443,
+				Kubernetes: guacamole.KubernetesSettings{
+					UseSSL:     true,
+					CACert:     "ca",
+					ClientCert: "cert",
+					ClientKey:  "key",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "Default port with insecure TLS",
+			kubeconfig: `{
+				"clusters": [{"cluster": {"server": "https://k8s.lab", "insecure-skip-tls-verify": true}}],
+				"users": [{"user": {}}]
+			}`,
+			expected: guacamole.Host{
+				Protocol: guacamole.ProtocolKubernetes,
+				IP:       "k8s.lab",
+				Port:     443,
+				Kubernetes: guacamole.KubernetesSettings{
+					UseSSL:     true,
+					IgnoreCert: true,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name:       "Multiple clusters",
+			kubeconfig: `{"clusters": [{}, {}], "users": [{}]}`,
+			expectErr:  true,
+		},
+		{
+			name: "Invalid certificate data",
+			kubeconfig: `{
+				"clusters": [{"cluster": {"server": "https://10.0.0.1:6443", "certificate-authority-data": "!!!"}}],
+				"users": [{"user": {}}]
+			}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := guacinator.HostFromKubeconfig([]byte(tc.kubeconfig))
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, host)
+			}
+		})
+	}
+}
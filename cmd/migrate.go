@@ -0,0 +1,151 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFrom   string
+	migrateTo     string
+	migrateDryRun bool
+
+	// migrateCmd represents the migrate command
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Copy the objects of one Guacamole instance to another.",
+		Long: `Copy the connection groups, connections, sharing profiles, user groups
+and users of one Guacamole instance to another, along with the permissions a
+manifest describes: system permissions and READ permissions on connections,
+connection groups and sharing profiles. Other permissions are not copied, and
+a warning on stderr names them.
+
+Instances are named by their context in the config file. Objects
+are matched by path and name, so identifiers and parent groups are remapped
+to the ones the target instance assigns. Objects only found on the target are
+left untouched, and so is the user migrate authenticates to the target as.
+User passwords cannot be read back from Guacamole, so migrated users must
+have their password set on the target before they can log in.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, err := connectInstance(cmd.Context(), migrateFrom)
+			if err != nil {
+				return err
+			}
+			dst, err := connectInstance(cmd.Context(), migrateTo)
+			if err != nil {
+				return err
+			}
+
+			return migrate(cmd.Context(), src, dst)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	f := migrateCmd.Flags()
+	f.StringVar(&migrateFrom, "from", "", "Name of the context to copy from.")
+	f.StringVar(&migrateTo, "to", "", "Name of the context to copy to.")
+	f.BoolVar(&migrateDryRun, "dry-run", false, "Show the changes migrate would make without touching the target.")
+
+	for _, flag := range []string{"from", "to"} {
+		if err := migrateCmd.MarkFlagRequired(flag); err != nil {
+			cobra.CheckErr(err)
+		}
+	}
+}
+
+// connectInstance establishes a client of the
+// instance of a named context.
+func connectInstance(ctx context.Context, name string) (*guacamole.Client, error) {
+	instance, err := findContext(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := contextGuacConfig(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := connectGuac(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("context %s: %w", name, err)
+	}
+
+	return client, nil
+}
+
+// migrate copies the objects of the source
+// client's instance to the target's.
+func migrate(ctx context.Context, src, dst *guacamole.Client) error {
+	source, err := fetchState(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to read instance %s: %w", migrateFrom, err)
+	}
+	// The target's user is never migrated, so neither are its permissions.
+	delete(source.dropped, "user "+dst.Username())
+	source.warnDropped(os.Stderr)
+	target, err := fetchState(ctx, dst)
+	if err != nil {
+		return fmt.Errorf("failed to read instance %s: %w", migrateTo, err)
+	}
+
+	desired := migrationManifest(&source.Manifest, dst.Username())
+	changes := manifest.Diff(desired, &target.Manifest)
+
+	if migrateDryRun {
+		return manifest.WritePlan(os.Stdout, changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("No changes, %s matches %s.\n", migrateTo, migrateFrom)
+		return nil
+	}
+
+	return applyChanges(ctx, desired, target, changes)
+}
+
+// migrationManifest returns the objects to copy to the
+// target, leaving out the user authenticated to the target
+// so its permissions are never changed.
+func migrationManifest(source *manifest.Manifest, targetUser string) *manifest.Manifest {
+	desired := *source
+	desired.Users = nil
+	for _, u := range source.Users {
+		if u.Username != targetUser {
+			desired.Users = append(desired.Users, u)
+		}
+	}
+
+	return &desired
+}
@@ -0,0 +1,43 @@
+package cmd_test
+
+import (
+	"testing"
+
+	guacinator "github.com/cowdogmoo/guacinator/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    int
+		expectErr bool
+	}{
+		{
+			name:      "Default length",
+			length:    24,
+			expectErr: false,
+		},
+		{
+			name:      "Too short",
+			length:    8,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			password, err := guacinator.GeneratePassword(tc.length)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, password, tc.length)
+
+				other, err := guacinator.GeneratePassword(tc.length)
+				require.NoError(t, err)
+				require.NotEqual(t, password, other)
+			}
+		})
+	}
+}
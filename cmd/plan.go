@@ -0,0 +1,75 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/spf13/cobra"
+)
+
+// errDrift is returned by plan when Guacamole does not match
+// the manifest, so that Execute can exit with a distinct code.
+var errDrift = errors.New("guacamole does not match the manifest")
+
+// driftExitCode is the exit code used when plan detects drift.
+const driftExitCode = 2
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes apply would make without touching Guacamole.",
+	Long: `Show the changes apply would make without touching Guacamole.
+
+Secrets are masked in the output. The command exits with 0 when
+Guacamole matches the manifest, 2 when there is drift and 1 on errors.`,
+	Args: cobra.NoArgs,
+	// Drift is reported through the exit code, not as an error message.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, _, changes, err := planManifest(cmd.Context(), manifestFile)
+		if err != nil {
+			return err
+		}
+
+		if err := manifest.WritePlan(os.Stdout, changes); err != nil {
+			return err
+		}
+
+		if len(changes) > 0 {
+			return errDrift
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	addGuacFlags(planCmd)
+
+	addManifestFlags(planCmd)
+}
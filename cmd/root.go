@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
 	log "github.com/cowdogmoo/guacinator/pkg/logging"
 	"github.com/l50/goutils/v2/sys"
 	"github.com/mitchellh/go-homedir"
@@ -15,10 +20,11 @@ import (
 	"github.com/spf13/viper"
 )
 
-const (
-	defaultConfigName = "guacinator-config.yaml"
-	defaultConfigType = "yaml"
-)
+const defaultConfigType = "yaml"
+
+// interruptedExitCode is the exit code of a command
+// cancelled by SIGINT or SIGTERM, as shells report it.
+const interruptedExitCode = 130
 
 var (
 	//go:embed config/*
@@ -29,90 +35,141 @@ var (
 
 	debug bool
 
+	// version is the guacinator release, set at build time with
+	// -ldflags "-X github.com/cowdogmoo/guacinator/cmd.version=v1.2.3".
+	version = "dev"
+
 	rootCmd = &cobra.Command{
-		Use:   "guacinator",
-		Short: "Command line utility to interact programmatically with Apache Guacamole.",
+		Use:     "guacinator",
+		Short:   "Command line utility to interact programmatically with Apache Guacamole.",
+		Version: version,
 	}
-
-	home, _          = homedir.Dir()
-	defaultConfigDir = filepath.Join(home, ".guacinator")
 )
 
+// skipConfigAnnotation marks the commands working on the config file
+// itself, such as config validate. For them, the config file is only
+// located: it is neither created nor read, and need not exist.
+const skipConfigAnnotation = "guacinator/skip-config"
+
 func init() {
-	cobra.OnInitialize(initConfig)
+	// Run the config setup of the root command before the
+	// hooks of subcommands, such as the Guacamole login.
+	cobra.EnableTraverseRunHooks = true
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		initConfig(cmd)
+	}
 	setupRootCmd(rootCmd)
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// initConfig reads in config file and ENV variables if set. The config
+// file is located as described by config.Locate, and the default one is
+// created from the embedded template when it does not exist yet, unless
+// cmd is annotated with skipConfigAnnotation.
+func initConfig(cmd *cobra.Command) {
 	viper.SetConfigType(defaultConfigType)
+	viper.SetEnvPrefix("guacinator")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	viper.AutomaticEnv()
+	viper.SetDefault("log.level", "info")
+	viper.SetDefault("log.log_path", "guacinator.log")
+	viper.SetDefault("guac.scheme", "https")
+	viper.SetDefault("guac.vnc_port", 5901)
+	viper.SetDefault("guac.retry.attempts", 3)
+	viper.SetDefault("guac.retry.min_backoff", "500ms")
+	viper.SetDefault("guac.retry.max_backoff", "10s")
 
 	home, err := homedir.Dir()
-	checkErr(err, "Failed to get home directory: %v")
+	exitErr(err, "Failed to get home directory: %v")
 
-	guacConfigDir = filepath.Join(home, defaultConfigDir)
-	guacConfigFile = filepath.Join(guacConfigDir, fmt.Sprintf("%s.%s", defaultConfigName, defaultConfigType))
+	path, explicit := config.Locate(guacConfigFile, home)
+	guacConfigFile = path
+	guacConfigDir = filepath.Dir(path)
+	if _, skip := cmd.Annotations[skipConfigAnnotation]; skip {
+		return
+	}
 
-	// Check if the config file exists, if not create the default config file
-	if _, err := os.Stat(guacConfigDir); os.IsNotExist(err) {
-		fmt.Printf("Config file not found, creating default config file at %s", guacConfigFile)
-		createConfig(guacConfigFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if explicit {
+			exitErr(fmt.Errorf("%s does not exist", path), "Can't read config: %v")
+		}
+		createConfig(path)
 	}
 
 	viper.SetConfigFile(guacConfigFile)
 
 	if err := viper.ReadInConfig(); err != nil {
-		checkErr(err, "Can't read config: %v")
+		exitErr(err, "Can't read config: %v")
 	}
 
-	if err := viper.Unmarshal(&guacCfg); err != nil {
-		checkErr(err, "Failed to unmarshal config: %v")
+	// Invalid values are reported once the logger is up rather than
+	// failing here, so config validate can point out every problem.
+	unmarshalErr := viper.Unmarshal(&cfg)
+
+	level := cfg.Log.Level
+	if cfg.Debug {
+		level = "debug"
 	}
+	err = log.Initialize(guacConfigDir, level, cfg.Log.LogPath)
+	exitErr(err, "Failed to initialize the logger: %v")
 
-	err = log.Initialize(guacConfigDir, cfg.Log.Level, cfg.Log.LogPath)
-	checkErr(err, "Failed to initialize the logger: %v")
+	if unmarshalErr != nil {
+		log.Warn("Ignoring invalid values in %s, run guacinator config validate for details: %v",
+			guacConfigFile, unmarshalErr)
+	}
 
 	// Check for required dependencies after initializing the logger
-	checkErr(depCheck(), "Dependency check failed")
+	checkErr(depCheck(), "Dependency check failed: %v")
 }
 
 func createConfig(cfgPath string) {
-	cfgDir := filepath.Dir(cfgPath)
+	exitErr(writeDefaultConfig(cfgPath), "Failed to create the default config: %v")
+	fmt.Fprintf(os.Stderr, "Default config file created at %s\n", cfgPath)
+}
 
-	// Ensure the configuration directory exists
-	if _, err := os.Stat(cfgDir); os.IsNotExist(err) {
-		fmt.Printf("Creating config directory %s", cfgDir)
-		checkErr(os.MkdirAll(cfgDir, os.ModePerm), "failed to create config directory %s: %v")
+// writeDefaultConfig writes the embedded default config to
+// cfgPath, creating its directory when needed.
+func writeDefaultConfig(cfgPath string) error {
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Write the default config file if it does not exist
-	if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
-		configFileData, err := configContentsFs.ReadFile(filepath.Join("config", "config.yaml"))
-		checkErr(err, "failed to read embedded config: %v")
-		checkErr(os.WriteFile(cfgPath, configFileData, 0644), "failed to write config to %s: %v")
-		fmt.Printf("Default config file created at %s", cfgPath)
-	} else {
-		fmt.Printf("Config file already exists at %s", cfgPath)
+	configFileData, err := configContentsFs.ReadFile("config/" + config.FileName)
+	if err != nil {
+		return fmt.Errorf("failed to read embedded config: %w", err)
 	}
+
+	if err := os.WriteFile(cfgPath, configFileData, 0600); err != nil {
+		return fmt.Errorf("failed to write config to %s: %w", cfgPath, err)
+	}
+
+	return nil
 }
 
 func setupRootCmd(cmd *cobra.Command) {
 	pf := cmd.PersistentFlags()
-	pf.StringVar(&guacConfigFile, "config", "", "config file (default is $HOME/.guacinator/guacinator-config.yaml)")
+	pf.StringVar(&guacConfigFile, "config", "", "config file (env GUACINATOR_CONFIG, default is $XDG_CONFIG_HOME/guacinator/guacinator-config.yaml if it exists, otherwise $HOME/.guacinator/guacinator-config.yaml)")
 	if err := viper.BindPFlag("config", pf.Lookup("config")); err != nil {
-		log.Error("Failed to bind the config flag: %v", err)
+		exitErr(err, "Failed to bind the config flag: %v")
 	}
 
 	pf.BoolVarP(
 		&debug, "debug", "d", false, "Show debug messages.")
 	if err := viper.BindPFlag("debug", pf.Lookup("debug")); err != nil {
-		checkErr(err, "Failed to bind the debug flag: %v")
+		exitErr(err, "Failed to bind the debug flag: %v")
 	}
 
 	cmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// exitErr reports an error on stderr and exits, for failures
+// that happen before the logger is initialized.
+func exitErr(err error, format string) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: "+format+"\n", err)
+		os.Exit(1)
+	}
+}
+
 func checkErr(err error, format string) {
 	if err != nil {
 		log.Error(format, err)
@@ -133,7 +190,43 @@ func depCheck() error {
 }
 
 // Execute runs the root cobra command. It checks for errors and exits
-// the program if any are encountered.
+// the program with a code describing the kind of error encountered.
+// SIGINT and SIGTERM cancel the context of the command, which stops
+// its requests to Guacamole and any changes it has yet to make.
 func Execute() {
-	checkErr(rootCmd.Execute(), "Command execution failed")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err == nil {
+		return
+	}
+
+	// Cobra already printed the error on stderr, which is all
+	// that can be done when it happened before the logger was up.
+	if !errors.Is(err, errDrift) && log.Initialized() {
+		log.Error("Command execution failed: %v", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// exitCode maps an error onto the exit code documented
+// for its kind, falling back to 1.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errDrift):
+		return driftExitCode
+	case errors.Is(err, guacamole.ErrAuthFailed):
+		return 3
+	case errors.Is(err, guacamole.ErrPermissionDenied):
+		return 4
+	case errors.Is(err, guacamole.ErrNotFound):
+		return 5
+	case errors.Is(err, guacamole.ErrConflict):
+		return 6
+	case errors.Is(err, context.Canceled):
+		return interruptedExitCode
+	default:
+		return 1
+	}
 }
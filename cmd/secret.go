@@ -0,0 +1,209 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// stdinRead records whether a secret was already read from
+// stdin, since stdin can only provide one secret per command.
+var stdinRead bool
+
+// addSecretFlags registers the flags reading a secret from a
+// file or stdin alongside the flag of the secret itself, so it
+// does not have to end up in shell history or ps output.
+//
+// **Parameters:**
+//
+// fs: The flag set holding the secret's flag.
+// name: The name of the secret's flag, such as password.
+// what: A description of the secret used in the flag help.
+func addSecretFlags(fs *pflag.FlagSet, name, what string) {
+	fs.String(name+"-file", "", fmt.Sprintf("Read the %s from a file.", what))
+	fs.Bool(name+"-stdin", false, fmt.Sprintf("Read the %s from stdin.", what))
+}
+
+// readSecret returns a secret from its flag, the file named by
+// --<name>-file, stdin when --<name>-stdin is set, or otherwise
+// the GUACINATOR_<NAME> environment variable.
+//
+// **Parameters:**
+//
+// cmd: The command holding the secret's flags.
+// name: The name of the secret's flag, such as password.
+//
+// **Returns:**
+//
+// string: The secret, empty when it was not provided.
+//
+// error: An error if several sources are given or a source cannot be read.
+func readSecret(cmd *cobra.Command, name string) (string, error) {
+	flags := cmd.Flags()
+
+	var given []string
+	for _, flag := range []string{name, name + "-file", name + "-stdin"} {
+		if flags.Lookup(flag) != nil && flags.Changed(flag) {
+			given = append(given, "--"+flag)
+		}
+	}
+	if len(given) > 1 {
+		return "", fmt.Errorf("only one of %s may be used", strings.Join(given, ", "))
+	}
+
+	switch {
+	case flags.Changed(name):
+		return flags.GetString(name)
+	case flags.Lookup(name+"-file") != nil && flags.Changed(name+"-file"):
+		path, err := flags.GetString(name + "-file")
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read --%s-file: %w", name, err)
+		}
+		return trimNewline(string(data)), nil
+	case flags.Lookup(name+"-stdin") != nil && flags.Changed(name+"-stdin"):
+		return readStdinSecret(name)
+	default:
+		return flagOrEnv(cmd, name), nil
+	}
+}
+
+func readStdinSecret(name string) (string, error) {
+	if stdinRead {
+		return "", errors.New("only one secret can be read from stdin")
+	}
+	stdinRead = true
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --%s-stdin: %w", name, err)
+	}
+
+	return trimNewline(string(data)), nil
+}
+
+// flagOrEnv returns the value of a string flag when it is set,
+// otherwise the GUACINATOR_<NAME> environment variable.
+func flagOrEnv(cmd *cobra.Command, name string) string {
+	if cmd.Flags().Changed(name) {
+		value, _ := cmd.Flags().GetString(name)
+		return value
+	}
+
+	return viper.GetString(name)
+}
+
+// trimNewline drops the line ending editors and
+// echo leave at the end of a secret.
+func trimNewline(s string) string {
+	return strings.TrimRight(s, "\r\n")
+}
+
+// requireSecret returns a secret like readSecret, prompting
+// for it on the terminal when it was not provided.
+//
+// **Parameters:**
+//
+// cmd: The command holding the secret's flags.
+// name: The name of the secret's flag, such as new-password.
+// what: A description of the secret used in the prompt and errors.
+// confirm: Whether the secret must be entered twice, for new passwords.
+//
+// **Returns:**
+//
+// string: The secret.
+//
+// error: An error if the secret is missing and stdin is not a terminal.
+func requireSecret(cmd *cobra.Command, name, what string, confirm bool) (string, error) {
+	secret, err := readSecret(cmd, name)
+	if err != nil || secret != "" {
+		return secret, err
+	}
+
+	if !canPrompt() {
+		return "", missingSecret(name, what)
+	}
+
+	return promptSecret(what, confirm)
+}
+
+// canPrompt reports whether secrets can be read
+// interactively, without echoing them.
+func canPrompt() bool {
+	return !stdinRead && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// promptSecret reads a secret from the terminal without
+// echoing it, asking twice when confirm is set.
+func promptSecret(what string, confirm bool) (string, error) {
+	secret, err := readHidden(fmt.Sprintf("Enter %s: ", what))
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", fmt.Errorf("the %s must not be empty", what)
+	}
+
+	if confirm {
+		again, err := readHidden(fmt.Sprintf("Confirm %s: ", what))
+		if err != nil {
+			return "", err
+		}
+		if again != secret {
+			return "", fmt.Errorf("the %s entries do not match", what)
+		}
+	}
+
+	return secret, nil
+}
+
+// readHidden prompts on stderr so the prompt never
+// mixes with output redirected from stdout.
+func readHidden(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from the terminal: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// missingSecret describes every way a missing secret can be provided.
+func missingSecret(name, what string) error {
+	return fmt.Errorf("a %s is required: use --%s, --%s-file, --%s-stdin or GUACINATOR_%s",
+		what, name, name, name, strings.ToUpper(strings.ReplaceAll(name, "-", "_")))
+}
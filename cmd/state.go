@@ -0,0 +1,288 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// instanceState holds the objects found in a Guacamole instance
+// along with the identifiers Guacamole assigned to them.
+type instanceState struct {
+	manifest.Manifest
+
+	// client is the client the state was read through.
+	client guacamole.GuacService
+
+	// groupIDs, connIDs and sharingIDs map object paths to identifiers.
+	groupIDs   map[string]string
+	connIDs    map[string]string
+	sharingIDs map[string]string
+
+	// dropped lists the permissions of each user and user group
+	// that the manifest cannot describe, keyed by holder.
+	dropped map[string][]string
+}
+
+// fetchState reads every connection group, connection, sharing
+// profile, user group and user visible to the client's user,
+// stopping early when ctx is cancelled.
+func fetchState(ctx context.Context, client guacamole.GuacService) (*instanceState, error) {
+	state := &instanceState{
+		client:     client,
+		groupIDs:   map[string]string{"": guacamole.RootGroup},
+		connIDs:    map[string]string{},
+		sharingIDs: map[string]string{},
+		dropped:    map[string][]string{},
+	}
+
+	tree, err := client.GetConnectionTree(ctx, guacamole.RootGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection tree: %w", err)
+	}
+	if err := state.walkGroup(ctx, tree, ""); err != nil {
+		return nil, err
+	}
+
+	if err := state.fetchSharingProfiles(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := state.fetchUserGroups(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := state.fetchUsers(ctx); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// walkGroup records the connections and child groups of a
+// connection group, reading each connection's parameters.
+func (s *instanceState) walkGroup(ctx context.Context, group types.GuacConnectionGroup, path string) error {
+	for _, conn := range group.ChildConnections {
+		full, err := s.client.GetConnection(ctx, conn.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to read connection %s: %w", conn.Name, err)
+		}
+
+		c := manifest.ConnectionFromGuac(full, path)
+		s.connIDs[c.Path()] = conn.Identifier
+		s.Connections = append(s.Connections, c)
+	}
+
+	for _, child := range group.ChildGroups {
+		g := manifest.ConnectionGroupFromGuac(child, path)
+		s.groupIDs[g.Path()] = child.Identifier
+		s.ConnectionGroups = append(s.ConnectionGroups, g)
+
+		if err := s.walkGroup(ctx, child, g.Path()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *instanceState) fetchSharingProfiles(ctx context.Context) error {
+	profiles, err := s.client.ListSharingProfiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list sharing profiles: %w", err)
+	}
+
+	connPaths := invert(s.connIDs)
+	for id, profile := range profiles {
+		params, err := s.client.GetSharingProfileParameters(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to read sharing profile %s: %w", profile.Name, err)
+		}
+
+		p := manifest.SharingProfile{
+			Name:       profile.Name,
+			Connection: connPaths[profile.PrimaryConnectionIdentifier],
+			Parameters: nonEmpty(params),
+			Attributes: nonEmpty(profile.Attributes),
+		}
+		s.sharingIDs[p.Path()] = id
+		s.SharingProfiles = append(s.SharingProfiles, p)
+	}
+	sort.Slice(s.SharingProfiles, func(i, j int) bool {
+		return s.SharingProfiles[i].Path() < s.SharingProfiles[j].Path()
+	})
+
+	return nil
+}
+
+func (s *instanceState) fetchUserGroups(ctx context.Context) error {
+	groups, err := s.client.ListUserGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list user groups: %w", err)
+	}
+
+	for _, group := range groups {
+		data, err := s.client.GetUserGroupPermissions(ctx, group.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to get permissions of user group %s: %w", group.Identifier, err)
+		}
+
+		perms := s.permissions("user group "+group.Identifier, data)
+		s.UserGroups = append(s.UserGroups, manifest.UserGroupFromGuac(group, perms))
+	}
+
+	return nil
+}
+
+func (s *instanceState) fetchUsers(ctx context.Context) error {
+	users, err := s.client.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	for _, u := range users {
+		data, err := s.client.GetUserPermissions(ctx, u.Username)
+		if err != nil {
+			return fmt.Errorf("failed to get permissions of user %s: %w", u.Username, err)
+		}
+
+		groups, err := s.client.GetUserGroupMembership(ctx, u.Username)
+		if err != nil {
+			return fmt.Errorf("failed to get user groups of user %s: %w", u.Username, err)
+		}
+
+		// Guacamole lets every user read their own account,
+		// so that permission needs no describing.
+		if self := data.UserPermissions[u.Username]; len(self) == 1 && self[0] == "READ" {
+			delete(data.UserPermissions, u.Username)
+		}
+
+		perms := s.permissions("user "+u.Username, data)
+		s.Users = append(s.Users, manifest.UserFromGuac(u, groups, perms))
+	}
+
+	return nil
+}
+
+// permissions converts Guacamole permission data into
+// manifest permissions using the paths known to the state,
+// recording those of holder the manifest cannot describe.
+func (s *instanceState) permissions(holder string, data types.GuacPermissionData) *manifest.Permissions {
+	groupPaths := invert(s.groupIDs)
+	groupPaths[guacamole.RootGroup] = guacamole.RootGroup
+
+	perms, dropped := manifest.PermissionsFromGuac(data, invert(s.connIDs), groupPaths, invert(s.sharingIDs))
+	if len(dropped) > 0 {
+		s.dropped[holder] = dropped
+	}
+
+	return perms
+}
+
+// warnDropped tells on stderr which permissions were left
+// out of the state because the manifest cannot describe them.
+func (s *instanceState) warnDropped(w io.Writer) {
+	holders := make([]string, 0, len(s.dropped))
+	for holder := range s.dropped {
+		holders = append(holders, holder)
+	}
+	sort.Strings(holders)
+
+	for _, holder := range holders {
+		dropped := s.dropped[holder]
+		examples := dropped[:min(len(dropped), 3)]
+		if len(dropped) > len(examples) {
+			examples = append(examples[:len(examples):len(examples)], "...")
+		}
+		fmt.Fprintf(w, "Warning: %d permissions of %s cannot be described by a manifest and are left out: %s\n",
+			len(dropped), holder, strings.Join(examples, ", "))
+	}
+}
+
+// groupID resolves the identifier of a connection group
+// path, where both an empty path and ROOT refer to the root.
+func (s *instanceState) groupID(path string) (string, error) {
+	if path == guacamole.RootGroup {
+		path = ""
+	}
+
+	id, ok := s.groupIDs[path]
+	if !ok {
+		return "", fmt.Errorf("connection group %s does not exist", path)
+	}
+
+	return id, nil
+}
+
+// sharingID resolves the identifier of a sharing profile path.
+func (s *instanceState) sharingID(path string) (string, error) {
+	id, ok := s.sharingIDs[path]
+	if !ok {
+		return "", fmt.Errorf("sharing profile %s does not exist", path)
+	}
+
+	return id, nil
+}
+
+// connID resolves the identifier of a connection path.
+func (s *instanceState) connID(path string) (string, error) {
+	id, ok := s.connIDs[path]
+	if !ok {
+		return "", fmt.Errorf("connection %s does not exist", path)
+	}
+
+	return id, nil
+}
+
+// nonEmpty drops the unset values Guacamole
+// returns for parameters and attributes.
+func nonEmpty(m map[string]string) map[string]string {
+	ret := map[string]string{}
+	for k, v := range m {
+		if v != "" {
+			ret[k] = v
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+
+	return ret
+}
+
+func invert(m map[string]string) map[string]string {
+	inverted := make(map[string]string, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+
+	return inverted
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	log "github.com/cowdogmoo/guacinator/pkg/logging"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// tokenCacheFile is the name of the token cache within ~/.guacinator.
+const tokenCacheFile = "tokens.json"
+
+// cachedToken is a Guacamole auth token kept between invocations.
+//
+// **Attributes:**
+//
+// Token: The auth token.
+// DataSource: The data source Guacamole authenticated against.
+// AvailableDataSources: The data sources available to the user.
+type cachedToken struct {
+	Token                string   `json:"token"`
+	DataSource           string   `json:"data_source"`
+	AvailableDataSources []string `json:"available_data_sources"`
+}
+
+// tokenCache holds cached tokens keyed by user and instance URL.
+type tokenCache map[string]cachedToken
+
+var (
+	// loginCmd represents the login command
+	loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "Log in to Guacamole and cache the auth token for later commands.",
+		Long: `Log in to Guacamole and cache the auth token for later commands.
+
+Commands reuse the cached token for the same URL and user until Guacamole
+expires it, and then log in again. Tokens are cached in
+~/.guacinator/tokens.json, readable only by its owner.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := readGuacFlags(cmd); err != nil {
+				return err
+			}
+			if err := waitForGuac(cmd, guacCfg); err != nil {
+				return err
+			}
+
+			if _, err := login(cmd.Context(), guacCfg, loadTokenCache()); err != nil {
+				return err
+			}
+
+			fmt.Printf("Logged in to %s as %s\n", guacCfg.URL, guacCfg.Username)
+
+			return nil
+		},
+	}
+
+	// logoutCmd represents the logout command
+	logoutCmd = &cobra.Command{
+		Use:   "logout",
+		Short: "Revoke the cached auth token of a Guacamole user.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := readGuacFlags(cmd); err != nil {
+				return err
+			}
+
+			return logout(cmd.Context(), guacCfg)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(loginCmd, logoutCmd)
+
+	addConnectionFlags(loginCmd)
+	addConnectionFlags(logoutCmd)
+}
+
+// logout revokes the cached token of the user in cfg
+// and removes it from the cache.
+func logout(ctx context.Context, cfg guacamole.Config) error {
+	cache := loadTokenCache()
+	key := tokenCacheKey(cfg)
+
+	cached, ok := cache[key]
+	if !ok {
+		fmt.Printf("Not logged in to %s as %s\n", cfg.URL, cfg.Username)
+		return nil
+	}
+
+	client, err := guacamole.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	// A token Guacamole already expired needs no revoking.
+	err = client.RevokeToken(ctx, cached.Token)
+	if err != nil && !errors.Is(err, guacamole.ErrNotFound) && !errors.Is(err, guacamole.ErrAuthFailed) {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	delete(cache, key)
+	if err := cache.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged out of %s as %s\n", cfg.URL, cfg.Username)
+
+	return nil
+}
+
+// tokenCacheKey identifies the token of a user on an instance.
+func tokenCacheKey(cfg guacamole.Config) string {
+	return cfg.Username + "@" + cfg.URL
+}
+
+func newCachedToken(auth types.AuthenticationResponse) cachedToken {
+	return cachedToken{
+		Token:                auth.AuthToken,
+		DataSource:           auth.DataSource,
+		AvailableDataSources: auth.AvailableDataSources,
+	}
+}
+
+func (t cachedToken) authResponse() types.AuthenticationResponse {
+	return types.AuthenticationResponse{
+		AuthToken:            t.Token,
+		DataSource:           t.DataSource,
+		AvailableDataSources: t.AvailableDataSources,
+	}
+}
+
+// tokenCachePath returns the path of the token cache, which stays
+// in ~/.guacinator wherever the config file is, falling back to the
+// config directory when the home directory is unknown.
+func tokenCachePath() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		return filepath.Join(guacConfigDir, tokenCacheFile)
+	}
+
+	return filepath.Join(home, config.DirName, tokenCacheFile)
+}
+
+// loadTokenCache reads the token cache, starting
+// an empty one when it is missing or unreadable.
+func loadTokenCache() tokenCache {
+	cache := tokenCache{}
+
+	data, err := os.ReadFile(tokenCachePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug("Failed to read token cache: %v", err)
+		}
+		return cache
+	}
+
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Debug("Ignoring invalid token cache: %v", err)
+		return tokenCache{}
+	}
+
+	return cache
+}
+
+// save writes the token cache so only its owner can read it.
+func (c tokenCache) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := tokenCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	// WriteFile keeps the mode of an existing file.
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// userAgent identifies guacinator in every request to Guacamole.
+var userAgent = "guacinator/" + version
+
+// instanceSettings describes how to reach a Guacamole instance.
+//
+// **Attributes:**
+//
+// TLS: The TLS settings of the instance.
+// Proxy: The URL of the HTTP proxy to use instead of the one from the environment.
+// Headers: Extra headers to send with every request.
+// Retry: How to retry requests the instance could not answer.
+// Timeout: How long each request may take, 0 for no limit.
+type instanceSettings struct {
+	TLS     config.TLSConfig
+	Proxy   string
+	Headers map[string]string
+	Retry   config.RetryConfig
+	Timeout time.Duration
+}
+
+// waitForGuac polls Guacamole until its API answers when
+// --wait-ready is set, so guacinator can run right after
+// Guacamole is deployed.
+//
+// **Parameters:**
+//
+// cmd: The command holding the --wait-ready flag.
+// instance: The config of the Guacamole instance.
+//
+// **Returns:**
+//
+// error: An error if Guacamole is not ready within the
+// --wait-ready duration.
+func waitForGuac(cmd *cobra.Command, instance guacamole.Config) error {
+	wait, err := cmd.Flags().GetDuration("wait-ready")
+	if err != nil || wait <= 0 {
+		return err
+	}
+
+	client, err := guacamole.New(instance)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), wait)
+	defer cancel()
+
+	if err := client.WaitReady(ctx); err != nil {
+		return fmt.Errorf("timed out after %s waiting for Guacamole at %s: %w", wait, instance.URL, err)
+	}
+
+	return nil
+}
+
+// addTransportFlags registers the flags controlling how
+// Guacamole is reached and its certificate verified.
+func addTransportFlags(fs *pflag.FlagSet) {
+	fs.Bool("insecure", false, "Skip verification of the TLS certificate of Guacamole (env GUACINATOR_INSECURE).")
+	fs.String("ca-file", "", "PEM bundle of CA certificates to trust besides the system ones (env GUACINATOR_CA_FILE).")
+	fs.String("tls-fingerprint", "", "SHA-256 fingerprint of the certificate of Guacamole to trust instead of verifying its chain (env GUACINATOR_TLS_FINGERPRINT).")
+	fs.String("client-cert", "", "PEM client certificate to present to Guacamole (env GUACINATOR_CLIENT_CERT).")
+	fs.String("client-key", "", "Key of the client certificate (env GUACINATOR_CLIENT_KEY).")
+	fs.String("proxy", "", "URL of the HTTP proxy to reach Guacamole through (env GUACINATOR_PROXY, default is HTTPS_PROXY or HTTP_PROXY).")
+	fs.String("base-path", "", "Path Guacamole is served under when --url has none, such as /guacamole (env GUACINATOR_BASE_PATH).")
+	fs.StringArray("header", nil, `Extra header to send with every request, as "Name: value" (repeatable).`)
+	fs.Duration("timeout", 0, "How long each request to Guacamole may take, 0 for no limit (env GUACINATOR_TIMEOUT, default is guac.timeout from the config).")
+	fs.Int("retries", 0, "How many times to retry a request Guacamole could not answer (env GUACINATOR_RETRIES, default is guac.retry.attempts from the config).")
+	fs.Duration("wait-ready", 0, "Wait up to this long for the Guacamole API to answer before running the command, such as 5m right after a deployment.")
+}
+
+// defaultSettings returns the base path and settings of
+// the selected context, or those under guac in the config
+// file when no context is selected. Retries and timeouts
+// always come from guac in the config file.
+func defaultSettings(ctx config.Context) (string, instanceSettings) {
+	settings := instanceSettings{Retry: cfg.Guac.Retry, Timeout: cfg.Guac.Timeout}
+	if ctx.Name != "" {
+		settings.TLS, settings.Proxy, settings.Headers = ctx.TLS, ctx.Proxy, ctx.Headers
+		return ctx.BasePath, settings
+	}
+
+	settings.TLS, settings.Proxy, settings.Headers = cfg.Guac.TLS, cfg.Guac.Proxy, cfg.Guac.Headers
+
+	return cfg.Guac.BasePath, settings
+}
+
+// readTransportFlags overlays the proxy and header flags,
+// or their GUACINATOR_* variables, on base.
+func readTransportFlags(cmd *cobra.Command, base instanceSettings) (instanceSettings, error) {
+	settings := base
+	settings.TLS = readTLSFlags(cmd, base.TLS)
+	settings.Proxy = firstNonEmpty(flagOrEnv(cmd, "proxy"), base.Proxy)
+	if err := readRetryFlags(cmd, &settings); err != nil {
+		return settings, err
+	}
+
+	flagHeaders, err := cmd.Flags().GetStringArray("header")
+	if err != nil {
+		return settings, err
+	}
+
+	settings.Headers = map[string]string{}
+	for name, value := range base.Headers {
+		settings.Headers[name] = value
+	}
+	for _, header := range flagHeaders {
+		name, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return settings, fmt.Errorf(`invalid header %q, use "Name: value"`, header)
+		}
+		settings.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return settings, nil
+}
+
+// readRetryFlags overlays the timeout and retries flags,
+// or their GUACINATOR_* variables, on settings.
+func readRetryFlags(cmd *cobra.Command, settings *instanceSettings) error {
+	var err error
+	if cmd.Flags().Changed("timeout") {
+		settings.Timeout, err = cmd.Flags().GetDuration("timeout")
+	} else if viper.IsSet("timeout") {
+		settings.Timeout = viper.GetDuration("timeout")
+	}
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("retries") {
+		settings.Retry.Attempts, err = cmd.Flags().GetInt("retries")
+	} else if viper.IsSet("retries") {
+		settings.Retry.Attempts = viper.GetInt("retries")
+	}
+
+	return err
+}
+
+// withPort adds port to a host that does not include
+// one, keeping any path after the host. A zero port
+// leaves the host unchanged.
+func withPort(host string, port int) string {
+	hostPort, path, hasPath := strings.Cut(host, "/")
+	if port == 0 || hostPort == "" {
+		return host
+	}
+	if _, _, err := net.SplitHostPort(hostPort); err == nil {
+		return host
+	}
+
+	hostPort = net.JoinHostPort(strings.Trim(hostPort, "[]"), strconv.Itoa(port))
+	if !hasPath {
+		return hostPort
+	}
+
+	return hostPort + "/" + path
+}
+
+// withBasePath appends the path Guacamole is served
+// under to a host that does not include a path.
+func withBasePath(host, basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" || strings.Contains(host, "/") {
+		return host
+	}
+
+	return host + "/" + basePath
+}
+
+// readTLSFlags overlays the TLS flags, or their
+// GUACINATOR_* variables, on base.
+func readTLSFlags(cmd *cobra.Command, base config.TLSConfig) config.TLSConfig {
+	tlsCfg := base
+	tlsCfg.CAFile = firstNonEmpty(flagOrEnv(cmd, "ca-file"), base.CAFile)
+	tlsCfg.Fingerprint = firstNonEmpty(flagOrEnv(cmd, "tls-fingerprint"), base.Fingerprint)
+	tlsCfg.CertFile = firstNonEmpty(flagOrEnv(cmd, "client-cert"), base.CertFile)
+	tlsCfg.KeyFile = firstNonEmpty(flagOrEnv(cmd, "client-key"), base.KeyFile)
+	if cmd.Flags().Changed("insecure") {
+		tlsCfg.Insecure, _ = cmd.Flags().GetBool("insecure")
+	} else if viper.IsSet("insecure") {
+		tlsCfg.Insecure = viper.GetBool("insecure")
+	}
+
+	return tlsCfg
+}
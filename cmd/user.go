@@ -0,0 +1,249 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// userCmd represents the user command
+	userCmd = &cobra.Command{
+		Use:   "user",
+		Short: "Manage Guacamole users.",
+	}
+
+	userCreateCmd = &cobra.Command{
+		Use:   "create USERNAME",
+		Short: "Create a Guacamole user.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			newPassword, err := requireSecret(cmd, "new-password", "password for "+args[0], true)
+			if err != nil {
+				return err
+			}
+
+			admin, err := cmd.Flags().GetBool("admin")
+			if err != nil {
+				return err
+			}
+
+			kind := "user"
+			if admin {
+				kind = "admin user"
+				err = guacService.CreateAdminUser(cmd.Context(), args[0], newPassword)
+			} else {
+				err = guacService.CreateUser(cmd.Context(), args[0], newPassword)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to create %s user in Guacamole: %w", args[0], err)
+			}
+
+			fmt.Printf("Successfully created %s %s\n", kind, args[0])
+
+			return nil
+		},
+	}
+
+	userListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the users in Guacamole.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users, err := guacService.ListUsers(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to list Guacamole users: %w", err)
+			}
+
+			rows := make([][]string, 0, len(users))
+			for _, u := range users {
+				rows = append(rows, []string{u.Username, u.Attributes.GuacFullName, strconv.Itoa(u.LastActive)})
+			}
+
+			return printTable([]string{"USERNAME", "FULL NAME", "LAST ACTIVE"}, rows)
+		},
+	}
+
+	userSetPasswordCmd = &cobra.Command{
+		Use:   "set-password USERNAME",
+		Short: "Change the password of a Guacamole user.",
+		Long: `Change the password of a Guacamole user.
+
+Users changing their own password do so with their old password, which
+defaults to the password used to log in. The passwords of other users
+are changed with --old-password when it is given, and otherwise reset by
+an administrator. A random password can be generated with
+--generate, and is written to stdout or --output-file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			newPassword, err := newUserPassword(cmd)
+			if err != nil {
+				return err
+			}
+
+			oldPassword, err := oldUserPassword(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			// Open the output file first, so a generated password
+			// is never set without a place to write it to.
+			out, err := openPasswordOutput(cmd)
+			if err != nil {
+				return err
+			}
+			if out != nil {
+				defer out.Close()
+			}
+
+			if err := guacService.SetUserPassword(cmd.Context(), args[0], oldPassword, newPassword); err != nil {
+				return fmt.Errorf("failed to set the password of %s: %w", args[0], err)
+			}
+			fmt.Fprintln(os.Stderr, "Successfully changed the password of "+args[0])
+
+			if generate, _ := cmd.Flags().GetBool("generate"); generate {
+				return writeGeneratedPassword(out, newPassword)
+			}
+
+			return nil
+		},
+	}
+
+	userDeleteCmd = &cobra.Command{
+		Use:   "delete USERNAME",
+		Short: "Delete a Guacamole user.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := guacService.DeleteGuacUser(cmd.Context(), args[0]); err != nil {
+				return fmt.Errorf("failed to delete %s from Guacamole: %w", args[0], err)
+			}
+
+			fmt.Println("Successfully deleted " + args[0])
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	addGuacFlags(userCmd)
+
+	userCmd.AddCommand(userCreateCmd, userListCmd, userSetPasswordCmd, userDeleteCmd)
+
+	userCreateCmd.Flags().StringP(
+		"new-password", "", "", "Password for the new user (env GUACINATOR_NEW_PASSWORD).")
+	addSecretFlags(userCreateCmd.Flags(), "new-password", "password for the new user")
+	userCreateCmd.Flags().BoolP(
+		"admin", "", false, "Grant the new user all system permissions.")
+
+	sf := userSetPasswordCmd.Flags()
+	sf.String("new-password", "", "New password for the user (env GUACINATOR_NEW_PASSWORD).")
+	addSecretFlags(sf, "new-password", "new password for the user")
+	sf.String("old-password", "", "Current password of the user, for a self-service change (required when changing your own, env GUACINATOR_OLD_PASSWORD).")
+	addSecretFlags(sf, "old-password", "current password of the user")
+	sf.Bool("generate", false, "Generate a random password instead of using --new-password.")
+	sf.Int("length", 24, "Length of the generated password.")
+	sf.String("output-file", "", "File to write the generated password to (default is stdout).")
+	userSetPasswordCmd.MarkFlagsMutuallyExclusive("new-password", "new-password-file", "new-password-stdin", "generate")
+}
+
+// newUserPassword returns the password set by set-password,
+// generating one when requested.
+func newUserPassword(cmd *cobra.Command) (string, error) {
+	generate, err := cmd.Flags().GetBool("generate")
+	if err != nil {
+		return "", err
+	}
+	if !generate {
+		return requireSecret(cmd, "new-password", "new password", true)
+	}
+
+	length, err := cmd.Flags().GetInt("length")
+	if err != nil {
+		return "", err
+	}
+
+	return GeneratePassword(length)
+}
+
+// oldUserPassword returns the old password for set-password. Users
+// changing their own password must provide it, and are prompted for it
+// on a terminal rather than reusing the password they logged in with,
+// which is unknown when a cached token is used.
+func oldUserPassword(cmd *cobra.Command, username string) (string, error) {
+	if username != guacCfg.Username {
+		return readSecret(cmd, "old-password")
+	}
+
+	return requireSecret(cmd, "old-password", "current password of "+username, false)
+}
+
+// openPasswordOutput opens the file named by --output-file, only
+// readable by its owner, when a generated password is written to
+// one. It returns nil when the password goes to stdout.
+func openPasswordOutput(cmd *cobra.Command) (*os.File, error) {
+	generate, err := cmd.Flags().GetBool("generate")
+	if err != nil {
+		return nil, err
+	}
+	outputFile, err := cmd.Flags().GetString("output-file")
+	if err != nil || !generate || outputFile == "" {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", outputFile, err)
+	}
+	// The mode given to OpenFile does not apply to existing files.
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to restrict the permissions of %s: %w", outputFile, err)
+	}
+
+	return f, nil
+}
+
+// writeGeneratedPassword outputs a generated password
+// to stdout, or to out when it is not nil, replacing
+// whatever out held.
+func writeGeneratedPassword(out *os.File, newPassword string) error {
+	if out == nil {
+		fmt.Println(newPassword)
+		return nil
+	}
+
+	if err := out.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write password to %s: %w", out.Name(), err)
+	}
+	if _, err := out.WriteString(newPassword + "\n"); err != nil {
+		return fmt.Errorf("failed to write password to %s: %w", out.Name(), err)
+	}
+
+	return out.Close()
+}
@@ -21,15 +21,135 @@ THE SOFTWARE.
 */
 package config
 
+import "time"
+
 // Config is the struct that holds the configuration for the application.
 //
 // **Attributes:**
 //
 // Debug: A boolean flag to enable debug mode.
 // Log: The configuration for the logger.
+// Guac: The defaults used to connect to Guacamole.
+// CurrentContext: The name of the context used when none is selected.
+// Contexts: The Guacamole instances guacinator can connect to.
 type Config struct {
-	Debug bool      `mapstructure:"debug"`
-	Log   LogConfig `mapstructure:"log"`
+	Debug          bool       `mapstructure:"debug"`
+	Log            LogConfig  `mapstructure:"log"`
+	Guac           GuacConfig `mapstructure:"guac"`
+	CurrentContext string     `mapstructure:"current_context"`
+	Contexts       []Context  `mapstructure:"contexts"`
+}
+
+// GuacConfig holds the defaults used to connect to Guacamole.
+//
+// **Attributes:**
+//
+// Scheme: The scheme used when a URL does not include one, http or https.
+// URL: The URL of the Guacamole instance.
+// BasePath: The path Guacamole is served under when the URL has none,
+// such as /guacamole behind a shared reverse proxy.
+// Port: The port of the Guacamole instance.
+// VNCPort: The port new VNC connections use when none is given.
+// DataSource: The data source to operate on.
+// Timeout: How long to wait for each response from Guacamole.
+// Retry: How to retry requests Guacamole could not answer.
+// TLS: The TLS settings used to connect to Guacamole.
+// Proxy: The URL of the HTTP proxy to reach Guacamole through,
+// instead of the one from HTTPS_PROXY and HTTP_PROXY.
+// Headers: Extra headers sent with every request to Guacamole.
+type GuacConfig struct {
+	Scheme     string            `mapstructure:"scheme"`
+	URL        string            `mapstructure:"url"`
+	BasePath   string            `mapstructure:"base_path"`
+	Port       int               `mapstructure:"port"`
+	VNCPort    int               `mapstructure:"vnc_port"`
+	DataSource string            `mapstructure:"data_source"`
+	Timeout    time.Duration     `mapstructure:"timeout"`
+	Retry      RetryConfig       `mapstructure:"retry"`
+	TLS        TLSConfig         `mapstructure:"tls"`
+	Proxy      string            `mapstructure:"proxy"`
+	Headers    map[string]string `mapstructure:"headers"`
+}
+
+// RetryConfig controls how requests are retried while Guacamole
+// is unreachable, overloaded or failing with a server error.
+//
+// **Attributes:**
+//
+// Attempts: How many times to retry a request, 0 to never retry.
+// MinBackoff: The delay before the first retry, doubled for each one after.
+// MaxBackoff: The longest delay between two attempts.
+type RetryConfig struct {
+	Attempts   int           `mapstructure:"attempts"`
+	MinBackoff time.Duration `mapstructure:"min_backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+}
+
+// TLSConfig holds the TLS settings used to connect to Guacamole.
+//
+// **Attributes:**
+//
+// Insecure: Skip verification of the certificate of Guacamole.
+// CAFile: A PEM file of CA certificates to trust besides the system ones.
+// Fingerprint: The SHA-256 fingerprint of the certificate of Guacamole,
+// which is trusted instead of verifying its chain.
+// CertFile: A PEM client certificate to present.
+// KeyFile: The key of the client certificate.
+type TLSConfig struct {
+	Insecure    bool   `mapstructure:"insecure"`
+	CAFile      string `mapstructure:"ca_file"`
+	Fingerprint string `mapstructure:"fingerprint"`
+	CertFile    string `mapstructure:"cert_file"`
+	KeyFile     string `mapstructure:"key_file"`
+}
+
+// Context describes a Guacamole instance and how to
+// authenticate with it, like a kubectl context.
+//
+// **Attributes:**
+//
+// Name: The name used to select the context.
+// URL: The URL of the Guacamole instance.
+// Username: The user to authenticate as.
+// PasswordFile: A file holding the password of the user.
+// PasswordEnv: An environment variable holding the password of the user.
+// DataSource: The data source to operate on.
+// BasePath: The path Guacamole is served under when the URL has none.
+// TLS: The TLS settings used to connect to the instance.
+// Proxy: The URL of the HTTP proxy to reach the instance through.
+// Headers: Extra headers sent with every request to the instance.
+type Context struct {
+	Name         string            `mapstructure:"name"`
+	URL          string            `mapstructure:"url"`
+	Username     string            `mapstructure:"username"`
+	PasswordFile string            `mapstructure:"password_file"`
+	PasswordEnv  string            `mapstructure:"password_env"`
+	DataSource   string            `mapstructure:"data_source"`
+	BasePath     string            `mapstructure:"base_path"`
+	TLS          TLSConfig         `mapstructure:"tls"`
+	Proxy        string            `mapstructure:"proxy"`
+	Headers      map[string]string `mapstructure:"headers"`
+}
+
+// FindContext looks up a context by name.
+//
+// **Parameters:**
+//
+// name: The name of the context.
+//
+// **Returns:**
+//
+// Context: The context, if found.
+//
+// bool: Whether the context was found.
+func (c Config) FindContext(name string) (Context, bool) {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx, true
+		}
+	}
+
+	return Context{}, false
 }
 
 // LogConfig stores the configuration for the logger.
@@ -0,0 +1,183 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindContext(t *testing.T) {
+	cfg := config.Config{
+		Contexts: []config.Context{
+			{Name: "staging", URL: "https://guacamole-staging.techvomit.xyz"},
+			{Name: "prod", URL: "https://guacamole.techvomit.xyz"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		context  string
+		expected string
+		found    bool
+	}{
+		{name: "Configured context", context: "prod", expected: "https://guacamole.techvomit.xyz", found: true},
+		{name: "Unknown context", context: "dev", found: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, found := cfg.FindContext(tc.context)
+			require.Equal(t, tc.found, found)
+			require.Equal(t, tc.expected, ctx.URL)
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	home := t.TempDir()
+	xdgDir := t.TempDir()
+	xdgPath := filepath.Join(xdgDir, "guacinator", config.FileName)
+	require.NoError(t, os.MkdirAll(filepath.Dir(xdgPath), 0700))
+	require.NoError(t, os.WriteFile(xdgPath, []byte("---\n"), 0600))
+
+	tests := []struct {
+		name             string
+		flagPath         string
+		envPath          string
+		xdgConfigHome    string
+		expected         string
+		expectedExplicit bool
+	}{
+		{
+			name:             "Flag wins over everything",
+			flagPath:         "/etc/guacinator.yaml",
+			envPath:          "/opt/guacinator.yaml",
+			xdgConfigHome:    xdgDir,
+			expected:         "/etc/guacinator.yaml",
+			expectedExplicit: true,
+		},
+		{
+			name:             "Environment wins over XDG",
+			envPath:          "/opt/guacinator.yaml",
+			xdgConfigHome:    xdgDir,
+			expected:         "/opt/guacinator.yaml",
+			expectedExplicit: true,
+		},
+		{
+			name:          "Existing XDG config",
+			xdgConfigHome: xdgDir,
+			expected:      xdgPath,
+		},
+		{
+			name:          "Home directory by default",
+			xdgConfigHome: t.TempDir(),
+			expected:      filepath.Join(home, ".guacinator", config.FileName),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(config.EnvFile, tc.envPath)
+			t.Setenv("XDG_CONFIG_HOME", tc.xdgConfigHome)
+
+			path, explicit := config.Locate(tc.flagPath, home)
+			require.Equal(t, tc.expected, path)
+			require.Equal(t, tc.expectedExplicit, explicit)
+		})
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected []config.Problem
+	}{
+		{
+			name: "Valid config",
+			contents: `---
+log:
+  level: info
+guac:
+  scheme: https
+  url: guacamole.techvomit.xyz
+  port: 443
+  timeout: 30s
+  base_path: /guacamole
+  proxy: http://proxy.techvomit.xyz:3128
+  headers:
+    X-Forwarded-User: guacadmin
+current_context: lab
+contexts:
+  - name: lab
+    url: https://guacamole.techvomit.xyz:8443
+`,
+		},
+		{
+			name: "Ports set to 0",
+			contents: `---
+guac:
+  url: guacamole.techvomit.xyz
+  port: 0
+  vnc_port: 0
+`,
+			expected: []config.Problem{
+				{Key: "guac.port", Line: 4, Message: "must be between 1 and 65535"},
+				{Key: "guac.vnc_port", Line: 5, Message: "must be between 1 and 65535"},
+			},
+		},
+		{
+			name: "Every problem is reported",
+			contents: `---
+log:
+  level: verbose
+guac:
+  scheme: ftp
+  port: 70000
+  vnc_port: five
+  timeout: -1s
+  tls:
+    cert_file: /nonexistent/client.pem
+current_context: prod
+contexts:
+  - name: lab
+    url: https://guacamole.techvomit.xyz
+  - name: lab
+    password_file: lab-password
+    password_env: LAB_PASSWORD
+    proxy: proxy.techvomit.xyz
+    headers:
+      "Bad Header": x
+`,
+			expected: []config.Problem{
+				{Key: "guac.vnc_port", Line: 7, Message: `cannot parse as int: strconv.ParseInt: parsing "five": invalid syntax`},
+				{Key: "log.level", Line: 3, Message: "must be one of debug, info, warn, error"},
+				{Key: "guac.port", Line: 6, Message: "must be between 1 and 65535"},
+				{Key: "guac.scheme", Line: 5, Message: "must be one of http, https"},
+				{Key: "guac.timeout", Line: 8, Message: "must not be negative"},
+				{Key: "guac.tls", Line: 9, Message: "cert_file and key_file must be set together"},
+				{Key: "guac.tls.cert_file", Line: 10, Message: "cannot read /nonexistent/client.pem: no such file or directory"},
+				{Key: "contexts[1].name", Line: 15, Message: "lab is already used by another context"},
+				{Key: "contexts[1].url", Line: 15, Message: "is required"},
+				{Key: "contexts[1]", Line: 15, Message: "password_file and password_env are mutually exclusive"},
+				{Key: "contexts[1].proxy", Line: 18, Message: "must be a URL with a scheme of http, https, socks5"},
+				{Key: "contexts[1].headers.bad header", Line: 19, Message: "is not a valid header name"},
+				{Key: "current_context", Line: 11, Message: "context prod is not configured"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), config.FileName)
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0600))
+
+			problems, err := config.ValidateFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, problems)
+		})
+	}
+}
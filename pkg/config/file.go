@@ -0,0 +1,216 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys are the config keys whose values are masked by MaskSecrets.
+var secretKeys = []string{"password", "token", "secret"}
+
+// SetKey sets a value in a YAML config file, keeping the
+// comments of the rest of the file. Blank lines are not kept.
+//
+// **Parameters:**
+//
+// path: The path to the config file.
+// key: The dotted path of the key to set, such as guac.scheme.
+// value: The value to set.
+//
+// **Returns:**
+//
+// error: An error if the file cannot be read, parsed or written.
+func SetKey(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		if node, err = child(node, part, yaml.MappingNode); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	leaf, err := child(node, parts[len(parts)-1], yaml.ScalarNode)
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	// Leave the tag empty so numbers and booleans are not quoted.
+	leaf.Value, leaf.Tag, leaf.Style = value, "", 0
+
+	out, err := encodeDocument(doc, data)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, info.Mode().Perm())
+}
+
+// MaskSecrets replaces the values of passwords, tokens,
+// secrets and HTTP headers in a YAML config with asterisks.
+// References to secrets, such as password_file and
+// password_env, are kept.
+//
+// **Parameters:**
+//
+// data: The contents of the config file.
+//
+// **Returns:**
+//
+// []byte: The config with its secrets masked.
+//
+// error: An error if the config is not valid YAML.
+func MaskSecrets(data []byte) ([]byte, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	maskNode(doc)
+
+	return encodeDocument(doc, data)
+}
+
+func maskNode(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if isSecretKey(key) {
+				maskScalar(value)
+			}
+			// Headers often carry credentials, such as an
+			// Authorization header, so all of their values are masked.
+			if key == "headers" && value.Kind == yaml.MappingNode {
+				for j := 1; j < len(value.Content); j += 2 {
+					maskScalar(value.Content[j])
+				}
+			}
+		}
+	}
+
+	for _, n := range node.Content {
+		maskNode(n)
+	}
+}
+
+func maskScalar(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		node.Value, node.Tag, node.Style = "********", "!!str", 0
+	}
+}
+
+// isSecretKey reports whether a key holds a secret, such
+// as password or host_password.
+func isSecretKey(key string) bool {
+	for _, secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDocument parses a YAML config, returning an
+// empty mapping document for an empty config.
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	return &doc, nil
+}
+
+// encodeDocument encodes a YAML config, keeping the
+// document start marker of the original if it had one.
+func encodeDocument(doc *yaml.Node, original []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if bytes.HasPrefix(original, []byte("---")) {
+		buf.WriteString("---\n")
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// child returns the value of a key in a mapping,
+// adding the key when it is missing.
+func child(mapping *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, error) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not inside a mapping", key)
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != kind {
+				return nil, fmt.Errorf("%s is not a %s", key, kindName(kind))
+			}
+			return value, nil
+		}
+	}
+
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+
+	return value, nil
+}
+
+func kindName(kind yaml.Kind) string {
+	if kind == yaml.MappingNode {
+		return "mapping"
+	}
+
+	return "value"
+}
@@ -0,0 +1,124 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `---
+debug: false
+
+# Logging settings
+log:
+  level: info # change to debug when troubleshooting
+`
+
+func TestSetKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:  "Existing key keeps comments",
+			key:   "log.level",
+			value: "debug",
+			expected: `---
+debug: false
+# Logging settings
+log:
+  level: debug # change to debug when troubleshooting
+`,
+			expectErr: false,
+		},
+		{
+			name:  "New nested key",
+			key:   "guac.data_source",
+			value: "mysql",
+			expected: `---
+debug: false
+# Logging settings
+log:
+  level: info # change to debug when troubleshooting
+guac:
+  data_source: mysql
+`,
+			expectErr: false,
+		},
+		{
+			name:  "Numbers are not quoted",
+			key:   "guac.port",
+			value: "8443",
+			expected: `---
+debug: false
+# Logging settings
+log:
+  level: info # change to debug when troubleshooting
+guac:
+  port: 8443
+`,
+			expectErr: false,
+		},
+		{
+			name:      "Key below a value",
+			key:       "debug.level",
+			value:     "true",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(testConfig), 0600))
+
+			err := config.SetKey(path, tc.key, tc.value)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(data))
+		})
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	contents := `guac:
+  password: hunter2 # admin password
+  headers:
+    Authorization: Bearer abc
+contexts:
+  - name: lab
+    password_file: ~/.guac-pw
+    host_password: s3cret
+    token: ""
+    headers:
+      X-Api-Key: k3y
+`
+	expected := `guac:
+  password: '********' # admin password
+  headers:
+    Authorization: '********'
+contexts:
+  - name: lab
+    password_file: ~/.guac-pw
+    host_password: '********'
+    token: ""
+    headers:
+      X-Api-Key: '********'
+`
+
+	masked, err := config.MaskSecrets([]byte(contents))
+	require.NoError(t, err)
+	require.Equal(t, expected, string(masked))
+}
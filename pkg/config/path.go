@@ -0,0 +1,80 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	// FileName is the name of the guacinator config file.
+	FileName = "guacinator-config.yaml"
+
+	// DirName is the directory in the home directory of the
+	// user holding the default config file and the token cache.
+	DirName = ".guacinator"
+
+	// EnvFile is the environment variable naming the config file.
+	EnvFile = "GUACINATOR_CONFIG"
+)
+
+// Locate returns the config file to load. The first of these is used:
+//
+//  1. flagPath, from the --config flag.
+//  2. The file named by GUACINATOR_CONFIG.
+//  3. guacinator/guacinator-config.yaml in $XDG_CONFIG_HOME, which
+//     defaults to ~/.config, if it exists.
+//  4. ~/.guacinator/guacinator-config.yaml.
+//
+// **Parameters:**
+//
+// flagPath: The config file passed with --config, if any.
+// home: The home directory of the user.
+//
+// **Returns:**
+//
+// string: The path of the config file.
+//
+// bool: Whether the file was chosen explicitly through
+// the flag or the environment, and so must already exist.
+func Locate(flagPath, home string) (string, bool) {
+	if flagPath != "" {
+		return flagPath, true
+	}
+
+	if path := os.Getenv(EnvFile); path != "" {
+		return path, true
+	}
+
+	xdgDir := os.Getenv("XDG_CONFIG_HOME")
+	if xdgDir == "" {
+		xdgDir = filepath.Join(home, ".config")
+	}
+	xdgPath := filepath.Join(xdgDir, "guacinator", FileName)
+	if _, err := os.Stat(xdgPath); err == nil {
+		return xdgPath, false
+	}
+
+	return filepath.Join(home, DirName, FileName), false
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// ClientConfig builds the TLS client config described by c.
+//
+// **Returns:**
+//
+// *tls.Config: The TLS client config.
+//
+// error: An error if the CA bundle, the fingerprint or
+// the client certificate cannot be loaded.
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.Insecure,
+	}
+
+	if c.CAFile != "" {
+		pool, err := caPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.Fingerprint != "" {
+		pin, err := ParseFingerprint(c.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		// The pin replaces chain verification, so self-signed
+		// certificates can be trusted without a CA bundle.
+		tlsCfg.InsecureSkipVerify = true
+		tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPin(cs, pin)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := loadKeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// ParseFingerprint decodes a SHA-256 certificate fingerprint,
+// written as hex with or without colons and an optional
+// sha256: prefix.
+//
+// **Parameters:**
+//
+// fingerprint: The fingerprint to decode.
+//
+// **Returns:**
+//
+// []byte: The SHA-256 digest.
+//
+// error: An error if the fingerprint is not a SHA-256 digest.
+func ParseFingerprint(fingerprint string) ([]byte, error) {
+	s := strings.TrimPrefix(strings.ToLower(fingerprint), "sha256:")
+	pin, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(pin) != sha256.Size {
+		return nil, errors.New("fingerprint must be the hex SHA-256 digest of a certificate")
+	}
+
+	return pin, nil
+}
+
+// verifyPin checks the certificate of the server against a pinned digest.
+func verifyPin(cs tls.ConnectionState, pin []byte) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	digest := sha256.Sum256(cs.PeerCertificates[0].Raw)
+	if !bytes.Equal(digest[:], pin) {
+		return fmt.Errorf("certificate fingerprint %s does not match the pinned fingerprint",
+			hex.EncodeToString(digest[:]))
+	}
+
+	return nil
+}
+
+// caPool returns the system CA certificates along with those in caFile.
+func caPool(caFile string) (*x509.CertPool, error) {
+	pem, err := readFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return pool, nil
+}
+
+// loadKeyPair loads a client certificate and its key.
+func loadKeyPair(certFile, keyFile string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, errors.New("a client certificate needs both a cert file and a key file")
+	}
+
+	certPEM, err := readFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyPEM, err := readFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client key: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// readFile reads a file, expanding a leading ~.
+func readFile(path string) ([]byte, error) {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(expanded)
+}
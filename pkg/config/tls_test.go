@@ -0,0 +1,157 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cowdogmoo/guacinator/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert writes a self-signed client certificate
+// and its key to dir, returning their paths.
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "guacinator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestTLSConfigClientConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	serverCert := server.Certificate()
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Raw}), 0600))
+	digest := sha256.Sum256(serverCert.Raw)
+	certFile, keyFile := writeClientCert(t, dir)
+
+	tests := []struct {
+		name           string
+		tlsCfg         config.TLSConfig
+		expectedStatus int
+		expectErr      bool
+	}{
+		{
+			name:      "Untrusted certificate is rejected",
+			tlsCfg:    config.TLSConfig{},
+			expectErr: true,
+		},
+		{
+			name:           "Insecure skips verification",
+			tlsCfg:         config.TLSConfig{Insecure: true},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "CA bundle",
+			tlsCfg:         config.TLSConfig{CAFile: caFile},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Matching fingerprint",
+			tlsCfg:         config.TLSConfig{Fingerprint: "sha256:" + hex.EncodeToString(digest[:])},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:      "Mismatched fingerprint",
+			tlsCfg:    config.TLSConfig{Fingerprint: hex.EncodeToString(make([]byte, sha256.Size))},
+			expectErr: true,
+		},
+		{
+			name:           "Client certificate",
+			tlsCfg:         config.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientCfg, err := tc.tlsCfg.ClientConfig()
+			require.NoError(t, err)
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg}}
+			resp, err := client.Get(server.URL)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tc.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestParseFingerprint(t *testing.T) {
+	digest := sha256.Sum256([]byte("guacamole"))
+	colons := ""
+	for i, b := range digest {
+		if i > 0 {
+			colons += ":"
+		}
+		colons += hex.EncodeToString([]byte{b})
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		expectErr   bool
+	}{
+		{name: "Plain hex", fingerprint: hex.EncodeToString(digest[:])},
+		{name: "Colons and prefix", fingerprint: "SHA256:" + colons},
+		{name: "Too short", fingerprint: "ab:cd", expectErr: true},
+		{name: "Not hex", fingerprint: "guacamole", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pin, err := config.ParseFingerprint(tc.fingerprint)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, digest[:], pin)
+		})
+	}
+}
@@ -0,0 +1,387 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"text", "json"}
+	schemes    = []string{"http", "https"}
+	// proxySchemes are the proxy schemes net/http supports.
+	proxySchemes = []string{"http", "https", "socks5"}
+	headerName   = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+	decodeField  = regexp.MustCompile(`'([^']+)' ?`)
+)
+
+// Problem is an issue found in a config.
+//
+// **Attributes:**
+//
+// Key: The dotted path of the offending key, such as contexts[0].url.
+// Line: The line of the key in the config file, or 0 if unknown.
+// Message: What is wrong with the key.
+type Problem struct {
+	Key     string
+	Line    int
+	Message string
+}
+
+// String formats the problem as key: message.
+func (p Problem) String() string {
+	if p.Key == "" {
+		return p.Message
+	}
+
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// Validate checks the values of a config.
+//
+// **Returns:**
+//
+// []Problem: Every problem found in the config, empty if it is valid.
+func (c Config) Validate() []Problem {
+	var problems []Problem
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Log.Level != "" && !slices.Contains(logLevels, strings.ToLower(c.Log.Level)) {
+		add("log.level", "must be one of %s", strings.Join(logLevels, ", "))
+	}
+	if c.Log.Format != "" && !slices.Contains(logFormats, c.Log.Format) {
+		add("log.format", "must be one of %s", strings.Join(logFormats, ", "))
+	}
+
+	problems = append(problems, c.Guac.validate()...)
+	problems = append(problems, c.validateContexts()...)
+
+	return problems
+}
+
+func (g GuacConfig) validate() []Problem {
+	var problems []Problem
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if g.Scheme != "" && !slices.Contains(schemes, g.Scheme) {
+		add("guac.scheme", "must be one of %s", strings.Join(schemes, ", "))
+	}
+	if err := checkURL(g.URL); err != nil {
+		add("guac.url", "%v", err)
+	}
+	// A zero port is left unset, ValidateFile rejects one set to 0.
+	for key, port := range map[string]int{"guac.port": g.Port, "guac.vnc_port": g.VNCPort} {
+		if port != 0 && (port < 1 || port > 65535) {
+			add(key, "must be between 1 and 65535")
+		}
+	}
+	if g.Timeout < 0 {
+		add("guac.timeout", "must not be negative")
+	}
+
+	problems = append(problems, g.Retry.validate("guac.retry")...)
+	problems = append(problems, g.TLS.validate("guac.tls")...)
+	problems = append(problems, validateHTTP("guac", g.Proxy, g.Headers)...)
+
+	slices.SortFunc(problems, func(a, b Problem) int { return strings.Compare(a.Key, b.Key) })
+
+	return problems
+}
+
+func (r RetryConfig) validate(prefix string) []Problem {
+	var problems []Problem
+	if r.Attempts < 0 {
+		problems = append(problems, Problem{Key: prefix + ".attempts", Message: "must not be negative"})
+	}
+	if r.MinBackoff < 0 || r.MaxBackoff < 0 {
+		problems = append(problems, Problem{Key: prefix, Message: "backoffs must not be negative"})
+	}
+	if r.MaxBackoff > 0 && r.MinBackoff > r.MaxBackoff {
+		problems = append(problems, Problem{Key: prefix + ".min_backoff", Message: "must not exceed max_backoff"})
+	}
+
+	return problems
+}
+
+func (t TLSConfig) validate(prefix string) []Problem {
+	var problems []Problem
+	for key, path := range map[string]string{
+		"ca_file":   t.CAFile,
+		"cert_file": t.CertFile,
+		"key_file":  t.KeyFile,
+	} {
+		if err := checkFile(path); err != nil {
+			problems = append(problems, Problem{Key: prefix + "." + key, Message: err.Error()})
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		problems = append(problems, Problem{Key: prefix, Message: "cert_file and key_file must be set together"})
+	}
+	if t.Fingerprint != "" {
+		if _, err := ParseFingerprint(t.Fingerprint); err != nil {
+			problems = append(problems, Problem{Key: prefix + ".fingerprint", Message: err.Error()})
+		}
+	}
+
+	return problems
+}
+
+// validateHTTP checks the proxy and headers of an instance.
+func validateHTTP(prefix, proxy string, headers map[string]string) []Problem {
+	var problems []Problem
+	if proxy != "" {
+		if u, err := url.Parse(proxy); err != nil || !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+			problems = append(problems, Problem{Key: prefix + ".proxy",
+				Message: fmt.Sprintf("must be a URL with a scheme of %s", strings.Join(proxySchemes, ", "))})
+		}
+	}
+
+	for name := range headers {
+		if !headerName.MatchString(name) {
+			problems = append(problems, Problem{Key: prefix + ".headers." + name, Message: "is not a valid header name"})
+		}
+	}
+
+	return problems
+}
+
+func (c Config) validateContexts() []Problem {
+	var problems []Problem
+	seen := map[string]bool{}
+	for i, ctx := range c.Contexts {
+		key := fmt.Sprintf("contexts[%d]", i)
+		switch {
+		case ctx.Name == "":
+			problems = append(problems, Problem{Key: key + ".name", Message: "is required"})
+		case seen[ctx.Name]:
+			problems = append(problems, Problem{Key: key + ".name", Message: fmt.Sprintf("%s is already used by another context", ctx.Name)})
+		}
+		seen[ctx.Name] = true
+
+		if ctx.URL == "" {
+			problems = append(problems, Problem{Key: key + ".url", Message: "is required"})
+		} else if err := checkURL(ctx.URL); err != nil {
+			problems = append(problems, Problem{Key: key + ".url", Message: err.Error()})
+		}
+		if ctx.PasswordFile != "" && ctx.PasswordEnv != "" {
+			problems = append(problems, Problem{Key: key, Message: "password_file and password_env are mutually exclusive"})
+		}
+		problems = append(problems, ctx.TLS.validate(key+".tls")...)
+		problems = append(problems, validateHTTP(key, ctx.Proxy, ctx.Headers)...)
+	}
+
+	if c.CurrentContext != "" && !seen[c.CurrentContext] {
+		problems = append(problems, Problem{Key: "current_context", Message: fmt.Sprintf("context %s is not configured", c.CurrentContext)})
+	}
+
+	return problems
+}
+
+// checkURL checks a Guacamole URL, which may leave out the scheme.
+func checkURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %w", err)
+	}
+	if !slices.Contains(schemes, u.Scheme) {
+		return fmt.Errorf("scheme must be one of %s", strings.Join(schemes, ", "))
+	}
+	if u.Hostname() == "" {
+		return errors.New("has no host")
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return errors.New("port must be between 1 and 65535")
+		}
+	}
+
+	return nil
+}
+
+// checkFile checks that an optional file exists.
+func checkFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(expanded); err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, errors.Unwrap(err))
+	}
+
+	return nil
+}
+
+// ValidateFile loads and validates a config file. Problems
+// carry the line of the offending key in the file.
+//
+// **Parameters:**
+//
+// path: The path to the config file.
+//
+// **Returns:**
+//
+// []Problem: Every problem found in the file, empty if it is valid.
+//
+// error: An error if the file cannot be read or is not valid YAML.
+func ValidateFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(string(data))); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	var c Config
+	problems := decodeProblems(v.Unmarshal(&c))
+	problems = append(problems, c.Validate()...)
+	problems = append(problems, zeroPorts(v, problems)...)
+
+	lines := keyLines(&doc)
+	for i := range problems {
+		problems[i].Line = lineOf(lines, problems[i].Key)
+	}
+
+	return problems, nil
+}
+
+// zeroPorts reports the ports set to 0 in a config, which
+// Validate cannot tell apart from ports left unset. Ports
+// that failed to decode are already reported.
+func zeroPorts(v *viper.Viper, reported []Problem) []Problem {
+	var problems []Problem
+	for _, key := range []string{"guac.port", "guac.vnc_port"} {
+		if !v.IsSet(key) || v.GetInt(key) != 0 || slices.ContainsFunc(reported, func(p Problem) bool { return p.Key == key }) {
+			continue
+		}
+		problems = append(problems, Problem{Key: key, Message: "must be between 1 and 65535"})
+	}
+
+	return problems
+}
+
+// decodeProblems turns the errors decoding a config,
+// one per line, into problems.
+func decodeProblems(err error) []Problem {
+	if err == nil {
+		return nil
+	}
+
+	var problems []Problem
+	for _, msg := range strings.Split(err.Error(), "\n") {
+		if msg = strings.TrimSpace(msg); msg == "" || strings.HasPrefix(msg, "decoding failed") {
+			continue
+		}
+
+		p := Problem{Message: msg}
+		if m := decodeField.FindStringSubmatch(msg); m != nil {
+			p.Key = m[1]
+			p.Message = strings.Replace(msg, m[0], "", 1)
+		}
+		problems = append(problems, p)
+	}
+
+	return problems
+}
+
+// lineOf returns the line of a key, falling back to its
+// parent when the key itself is missing from the file.
+func lineOf(lines map[string]int, key string) int {
+	for key != "" {
+		if line, ok := lines[key]; ok {
+			return line
+		}
+		key = key[:max(strings.LastIndexAny(key, ".["), 0)]
+	}
+
+	return 0
+}
+
+// keyLines maps the dotted path of every key in a YAML
+// document, such as contexts[0].url, to its line.
+func keyLines(doc *yaml.Node) map[string]int {
+	lines := map[string]int{}
+
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, n := range node.Content {
+				walk(n, prefix)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i].Value
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				lines[key] = node.Content[i].Line
+				walk(node.Content[i+1], key)
+			}
+		case yaml.SequenceNode:
+			for i, n := range node.Content {
+				key := fmt.Sprintf("%s[%d]", prefix, i)
+				lines[key] = n.Line
+				walk(n, key)
+			}
+		}
+	}
+	walk(doc, "")
+
+	return lines
+}
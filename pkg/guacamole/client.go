@@ -0,0 +1,371 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package guacamole
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// readyInterval is how often WaitReady polls Guacamole.
+const readyInterval = 2 * time.Second
+
+// Config describes a Guacamole instance and how to reach it.
+//
+// **Attributes:**
+//
+// URL: The URL Guacamole is served at, such as https://guac.example.com/guacamole.
+// Username: The user to log in as.
+// Password: The password of the user.
+// DataSource: The data source to operate on, empty for the one Guacamole
+// authenticated against.
+// TLS: The TLS client config, nil to verify Guacamole against the system CAs.
+// Proxy: The URL of the HTTP proxy to use instead of the one from the environment.
+// Headers: Extra headers to send with every request.
+// UserAgent: The User-Agent to send with every request.
+// Retry: How to retry requests Guacamole could not answer.
+// Timeout: How long each request may take, 0 for no limit.
+// Logf: Logs the retries of failed requests, nil to discard them.
+type Config struct {
+	URL        string
+	Username   string
+	Password   string
+	DataSource string
+	TLS        *tls.Config
+	Proxy      string
+	Headers    map[string]string
+	UserAgent  string
+	Retry      RetryPolicy
+	Timeout    time.Duration
+	Logf       func(format string, args ...interface{})
+}
+
+// logf logs through Logf when it is set.
+func (c Config) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// Client is a client of the Guacamole REST API. It is created
+// with New and authenticated with Login or Resume before use.
+//
+// **Attributes:**
+//
+// cfg: The config the client was created from.
+// http: The HTTP client sending every request.
+// token: The auth token of the session.
+// dataSource: The data source the client operates on.
+type Client struct {
+	cfg        Config
+	http       *http.Client
+	token      string
+	dataSource string
+}
+
+var _ GuacService = (*Client)(nil)
+
+// New creates a client of the Guacamole instance described by
+// cfg. No request is sent until the client is authenticated.
+//
+// **Parameters:**
+//
+// cfg: The instance to connect to and how to reach it.
+//
+// **Returns:**
+//
+// *Client: The unauthenticated client.
+//
+// error: An error if the URL or the proxy is invalid.
+func New(cfg Config) (*Client, error) {
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if u, err := url.Parse(cfg.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Guacamole URL %q", cfg.URL)
+	}
+
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{cfg: cfg, http: httpClient}, nil
+}
+
+// URL returns the URL of the Guacamole instance.
+func (c *Client) URL() string {
+	return c.cfg.URL
+}
+
+// Username returns the user the client authenticates as.
+func (c *Client) Username() string {
+	return c.cfg.Username
+}
+
+// DataSource returns the data source the client operates on.
+func (c *Client) DataSource() string {
+	return c.dataSource
+}
+
+// Login authenticates with the username and password of the
+// config, returning the response so the token can be reused
+// later with Resume.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the login.
+//
+// **Returns:**
+//
+// types.AuthenticationResponse: The auth token and the data sources
+// available to the user.
+//
+// error: An error if Guacamole rejects the credentials or the
+// requested data source is not available.
+func (c *Client) Login(ctx context.Context) (types.AuthenticationResponse, error) {
+	var auth types.AuthenticationResponse
+
+	form := url.Values{
+		"username": []string{c.cfg.Username},
+		"password": []string{c.cfg.Password},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL+"/api/tokens", strings.NewReader(form.Encode()))
+	if err != nil {
+		return auth, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := c.send(req, &auth); err != nil {
+		return auth, fmt.Errorf("failed to authenticate with Guacamole: %w", err)
+	}
+
+	if err := c.useToken(auth); err != nil {
+		return auth, err
+	}
+
+	return auth, nil
+}
+
+// Resume authenticates with a token from an earlier Login,
+// checking that Guacamole still accepts it.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the check.
+// auth: The response of the earlier Login.
+//
+// **Returns:**
+//
+// error: An error if the token expired or the requested
+// data source is not available.
+func (c *Client) Resume(ctx context.Context, auth types.AuthenticationResponse) error {
+	if err := c.useToken(auth); err != nil {
+		return err
+	}
+
+	var attributes interface{}
+	if err := c.call(ctx, http.MethodGet, "schema/userAttributes", nil, &attributes); err != nil {
+		c.token = ""
+		return err
+	}
+
+	return nil
+}
+
+// useToken makes the client operate with an auth token
+// on the data source selected from the config.
+func (c *Client) useToken(auth types.AuthenticationResponse) error {
+	dataSource, err := selectDataSource(auth, c.cfg.DataSource)
+	if err != nil {
+		return err
+	}
+	c.token, c.dataSource = auth.AuthToken, dataSource
+
+	return nil
+}
+
+// selectDataSource picks the data source to operate on, checking
+// that a requested one is available to the authenticated user.
+func selectDataSource(auth types.AuthenticationResponse, requested string) (string, error) {
+	if requested == "" {
+		return auth.DataSource, nil
+	}
+
+	if !types.StrSlice(auth.AvailableDataSources).Has(requested) {
+		return "", fmt.Errorf("data source %s is not available, must be one of %v",
+			requested, auth.AvailableDataSources)
+	}
+
+	return requested, nil
+}
+
+// RevokeToken invalidates an auth token, such as one
+// kept from an earlier Login.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+// token: The auth token to revoke.
+//
+// **Returns:**
+//
+// error: An error if the token cannot be revoked.
+func (c *Client) RevokeToken(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete,
+		fmt.Sprintf("%s/api/tokens/%s", c.cfg.URL, url.PathEscape(token)), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Guacamole-Token", token)
+
+	if err := c.send(req, nil); err != nil {
+		return err
+	}
+
+	if token == c.token {
+		c.token = ""
+	}
+
+	return nil
+}
+
+// WaitReady polls Guacamole until its API answers, so a client
+// can be used right after Guacamole is deployed.
+//
+// **Parameters:**
+//
+// ctx: The context bounding how long to wait.
+//
+// **Returns:**
+//
+// error: The last error seen when ctx is done before
+// Guacamole answers.
+func (c *Client) WaitReady(ctx context.Context) error {
+	// The list of languages is served without authentication
+	// once the web application has started.
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL+"/api/languages", nil)
+		if err != nil {
+			return err
+		}
+
+		err = c.send(req, nil)
+		if err == nil {
+			return nil
+		}
+		c.cfg.logf("Guacamole is not ready yet: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(readyInterval):
+		}
+	}
+}
+
+// url builds the URL of a REST endpoint
+// within the data source of the client.
+func (c *Client) url(path string) string {
+	return fmt.Sprintf("%s/api/session/data/%s/%s", c.cfg.URL, c.dataSource, path)
+}
+
+// call sends a request to an endpoint of the client's data
+// source, authenticated with the client's token. params is
+// sent as the JSON body of the request unless it is nil.
+func (c *Client) call(ctx context.Context, method, path string, params, result interface{}) error {
+	if c.token == "" {
+		return errors.New("the client is not authenticated, call Login or Resume first")
+	}
+
+	var body io.Reader
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path), body)
+	if err != nil {
+		return err
+	}
+	if params != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Guacamole-Token", c.token)
+
+	return c.send(req, result)
+}
+
+// send sends a request to the Guacamole REST API, decoding
+// a successful response into result and an error response
+// into an *APIError.
+//
+// **Parameters:**
+//
+// req: The request to send.
+// result: The value to decode the response into, nil to discard it.
+//
+// **Returns:**
+//
+// error: An *APIError if Guacamole rejected the request, or an error
+// if it could not be sent or decoded.
+func (c *Client) send(req *http.Request, result interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to Guacamole: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body from Guacamole: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// The body is not JSON when a proxy in front of Guacamole fails.
+		_ = json.Unmarshal(body, apiErr)
+		return apiErr
+	}
+
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to unmarshal response body from Guacamole: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package guacamole_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/stretchr/testify/require"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// newGuacServer serves the token and user endpoints of a
+// Guacamole instance, failing the first failures requests
+// to the user list with 503.
+func newGuacServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var listed atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"type":"INVALID_CREDENTIALS","message":"Permission Denied."}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.AuthenticationResponse{
+			AuthToken:            "token",
+			DataSource:           "postgresql",
+			AvailableDataSources: []string{"postgresql", "postgresql-shared"},
+		})
+	})
+	mux.HandleFunc("GET /api/session/data/postgresql/users", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Guacamole-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if listed.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "guacinator/test", r.Header.Get("User-Agent"))
+		require.Equal(t, "lab", r.Header.Get("X-Team"))
+		_, _ = w.Write([]byte(`{"alice":{"username":"alice"}}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, &listed
+}
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		dataSource string
+		failures   int32
+		attempts   int
+		expectErr  bool
+		errIs      error
+		expectList int32
+	}{
+		{
+			name:       "Lists users",
+			password:   "secret",
+			expectList: 1,
+		},
+		{
+			name:       "Retries unavailable Guacamole",
+			password:   "secret",
+			failures:   2,
+			attempts:   2,
+			expectList: 3,
+		},
+		{
+			name:       "Gives up after the retries",
+			password:   "secret",
+			failures:   2,
+			attempts:   1,
+			expectErr:  true,
+			expectList: 2,
+		},
+		{
+			name:      "Rejects invalid credentials",
+			password:  "wrong",
+			expectErr: true,
+			errIs:     guacamole.ErrAuthFailed,
+		},
+		{
+			name:       "Rejects unavailable data source",
+			password:   "secret",
+			dataSource: "ldap",
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, listed := newGuacServer(t, tc.failures)
+			client, err := guacamole.New(guacamole.Config{
+				URL:        srv.URL,
+				Username:   "guacadmin",
+				Password:   tc.password,
+				DataSource: tc.dataSource,
+				UserAgent:  "guacinator/test",
+				Headers:    map[string]string{"X-Team": "lab"},
+				Retry:      guacamole.RetryPolicy{Attempts: tc.attempts, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+			})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			_, err = client.Login(ctx)
+			if err == nil {
+				var users []types.GuacUser
+				users, err = client.ListUsers(ctx)
+				if err == nil {
+					require.Len(t, users, 1)
+					require.Equal(t, "alice", users[0].Username)
+				}
+			}
+
+			require.Equal(t, tc.expectList, listed.Load())
+			if !tc.expectErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			if tc.errIs != nil {
+				require.ErrorIs(t, err, tc.errIs)
+			}
+		})
+	}
+}
+
+func TestClientCancelled(t *testing.T) {
+	srv, listed := newGuacServer(t, 0)
+	client, err := guacamole.New(guacamole.Config{URL: srv.URL, Username: "guacadmin", Password: "secret"})
+	require.NoError(t, err)
+
+	_, err = client.Login(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.ListUsers(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, listed.Load())
+}
+
+func TestClientRetriesCreates(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		expectErr  bool
+		expected   int32
+	}{
+		{
+			name:      "Bad gateway",
+			status:    http.StatusBadGateway,
+			expectErr: true,
+			expected:  1,
+		},
+		{
+			name:      "Unavailable without Retry-After",
+			status:    http.StatusServiceUnavailable,
+			expectErr: true,
+			expected:  1,
+		},
+		{
+			name:       "Unavailable with Retry-After",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "0",
+			expected:   2,
+		},
+		{
+			name:       "Too many requests with Retry-After",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "0",
+			expected:   2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var created atomic.Int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /api/session/data/postgresql/users", func(w http.ResponseWriter, r *http.Request) {
+				if created.Add(1) == 1 {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"username":"bob"}`))
+			})
+			client := newLoggedInClient(t, mux, "guacadmin",
+				guacamole.RetryPolicy{Attempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+			err := client.CreateGuacUser(context.Background(), &types.GuacUser{Username: "bob"})
+			require.Equal(t, tc.expected, created.Load())
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClientNotAuthenticated(t *testing.T) {
+	client, err := guacamole.New(guacamole.Config{URL: "http://127.0.0.1:1"})
+	require.NoError(t, err)
+
+	_, err = client.ListUsers(context.Background())
+	require.Error(t, err)
+}
+
+// newLoggedInClient serves mux behind the token endpoint of a
+// Guacamole instance and returns a client logged in as username
+// on the postgresql data source, retrying as retry allows.
+func newLoggedInClient(t *testing.T, mux *http.ServeMux, username string, retry ...guacamole.RetryPolicy) *guacamole.Client {
+	t.Helper()
+
+	mux.HandleFunc("POST /api/tokens", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.AuthenticationResponse{
+			AuthToken:            "token",
+			Username:             r.FormValue("username"),
+			DataSource:           "postgresql",
+			AvailableDataSources: []string{"postgresql"},
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	cfg := guacamole.Config{URL: srv.URL, Username: username, Password: "secret"}
+	if len(retry) > 0 {
+		cfg.Retry = retry[0]
+	}
+	client, err := guacamole.New(cfg)
+	require.NoError(t, err)
+
+	_, err = client.Login(context.Background())
+	require.NoError(t, err)
+
+	return client
+}
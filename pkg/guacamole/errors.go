@@ -0,0 +1,111 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package guacamole
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrAuthFailed is returned when Guacamole rejects the credentials or token.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrPermissionDenied is returned when the user lacks permission for an operation.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrNotFound is returned when the requested object does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when an object conflicts with an existing
+	// one, such as a user or connection created with a name in use.
+	ErrConflict = errors.New("conflict")
+)
+
+// APIError is an error response returned by the Guacamole REST API.
+//
+// **Attributes:**
+//
+// StatusCode: The HTTP status code of the response.
+// Type: The Guacamole error type, such as INVALID_CREDENTIALS or NOT_FOUND.
+// Message: The human readable error message.
+// TranslatableMessage: The key and variables used by the web UI to render the message.
+type APIError struct {
+	StatusCode          int                 `json:"-"`
+	Type                string              `json:"type"`
+	Message             string              `json:"message"`
+	TranslatableMessage TranslatableMessage `json:"translatableMessage"`
+}
+
+// TranslatableMessage is the localizable form of a Guacamole error message.
+//
+// **Attributes:**
+//
+// Key: The translation key of the message.
+// Variables: The values substituted into the translated message.
+type TranslatableMessage struct {
+	Key       string                 `json:"key"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// Error renders the API error with its message and status.
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Type != "" {
+		return fmt.Sprintf("%s (HTTP %d %s)", msg, e.StatusCode, e.Type)
+	}
+
+	return fmt.Sprintf("%s (HTTP %d)", msg, e.StatusCode)
+}
+
+// Unwrap maps the API error onto the sentinel errors,
+// so callers can use errors.Is to act on its kind.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Type == "INVALID_CREDENTIALS" || e.Type == "INSUFFICIENT_CREDENTIALS" ||
+		e.StatusCode == http.StatusUnauthorized:
+		return ErrAuthFailed
+	case e.Type == "PERMISSION_DENIED" || e.StatusCode == http.StatusForbidden:
+		return ErrPermissionDenied
+	case e.Type == "NOT_FOUND" || e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict || e.duplicate():
+		return ErrConflict
+	default:
+		return nil
+	}
+}
+
+// duplicate reports whether the error is the one Guacamole
+// returns when creating an object whose name is taken, a
+// BAD_REQUEST such as `User "bob" already exists.`.
+func (e *APIError) duplicate() bool {
+	if e.Type != "BAD_REQUEST" {
+		return false
+	}
+	msg := strings.ToLower(e.Message)
+
+	return strings.Contains(msg, "already exists") || strings.Contains(msg, "must be unique")
+}
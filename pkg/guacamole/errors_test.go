@@ -0,0 +1,63 @@
+package guacamole_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *guacamole.APIError
+		expected error
+		message  string
+	}{
+		{
+			name:     "Invalid credentials",
+			err:      &guacamole.APIError{StatusCode: 403, Type: "INVALID_CREDENTIALS", Message: "Permission Denied."},
+			expected: guacamole.ErrAuthFailed,
+			message:  "Permission Denied. (HTTP 403 INVALID_CREDENTIALS)",
+		},
+		{
+			name:     "Permission denied",
+			err:      &guacamole.APIError{StatusCode: 403, Type: "PERMISSION_DENIED", Message: "Permission denied."},
+			expected: guacamole.ErrPermissionDenied,
+			message:  "Permission denied. (HTTP 403 PERMISSION_DENIED)",
+		},
+		{
+			name:     "Not found",
+			err:      &guacamole.APIError{StatusCode: 404, Type: "NOT_FOUND", Message: "No such user: \"bob\""},
+			expected: guacamole.ErrNotFound,
+			message:  "No such user: \"bob\" (HTTP 404 NOT_FOUND)",
+		},
+		{
+			name:     "Duplicate user",
+			err:      &guacamole.APIError{StatusCode: 400, Type: "BAD_REQUEST", Message: "User \"bob\" already exists."},
+			expected: guacamole.ErrConflict,
+			message:  "User \"bob\" already exists. (HTTP 400 BAD_REQUEST)",
+		},
+		{
+			name:     "Duplicate connection",
+			err:      &guacamole.APIError{StatusCode: 400, Type: "BAD_REQUEST", Message: "Connection names must be unique within a group."},
+			expected: guacamole.ErrConflict,
+			message:  "Connection names must be unique within a group. (HTTP 400 BAD_REQUEST)",
+		},
+		{
+			name:     "Conflict without a body",
+			err:      &guacamole.APIError{StatusCode: 409},
+			expected: guacamole.ErrConflict,
+			message:  "Conflict (HTTP 409)",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("failed to do something: %w", tc.err)
+			require.ErrorIs(t, wrapped, tc.expected)
+			require.EqualError(t, tc.err, tc.message)
+		})
+	}
+}
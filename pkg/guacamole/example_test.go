@@ -0,0 +1,57 @@
+package guacamole_test
+
+import (
+	"fmt"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+)
+
+func ExampleClient_CreateGuacamoleConnection() {
+	// Define a Host struct
+	host := guacamole.Host{Name: "Test", Protocol: guacamole.ProtocolVNC, IP: "192.168.1.1", Port: 5900, Password: "test_password"}
+
+	// Create an actual instance of Guacamole service
+	// client, _ := guacamole.New(guacamole.Config{URL: "https://guac.example.com/guacamole"})
+
+	// Call the function with the struct
+	// Here, instead of calling the actual function, we're just demonstrating how it would be used
+	fmt.Println("client.CreateGuacamoleConnection(ctx, host)")
+
+	_ = host
+	// Output:
+	// client.CreateGuacamoleConnection(ctx, host)
+}
+
+func ExampleClient_CreateAdminUser() {
+	// Define a username and password for the new admin user
+	username := "test_admin"
+	password := "test_password"
+
+	// Create an actual instance of Guacamole service
+	// client, _ := guacamole.New(guacamole.Config{URL: "https://guac.example.com/guacamole"})
+
+	// Call the function with the username and password
+	// Here, instead of calling the actual function, we're just demonstrating how it would be used
+	fmt.Println("client.CreateAdminUser(ctx, username, password)")
+
+	_ = username
+	_ = password
+	// Output:
+	// client.CreateAdminUser(ctx, username, password)
+}
+
+func ExampleClient_DeleteGuacUser() {
+	// Define a username for the Guacamole user to be deleted
+	username := "test_user"
+
+	// Create an actual instance of Guacamole service
+	// client, _ := guacamole.New(guacamole.Config{URL: "https://guac.example.com/guacamole"})
+
+	// Call the function with the username
+	// Here, instead of calling the actual function, we're just demonstrating how it would be used
+	fmt.Println("client.DeleteGuacUser(ctx, username)")
+
+	_ = username
+	// Output:
+	// client.DeleteGuacUser(ctx, username)
+}
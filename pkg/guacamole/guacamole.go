@@ -0,0 +1,563 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package guacamole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+const (
+	// RootGroup is the identifier of the root connection group.
+	RootGroup = "ROOT"
+
+	connectionsPath      = "connections"
+	connectionGroupsPath = "connectionGroups"
+	usersPath            = "users"
+	userGroupsPath       = "userGroups"
+)
+
+// GuacService represents the interface for interacting with the Guacamole API.
+//
+// **Methods:**
+// CreateGuacamoleConnection:   Creates a new Guacamole connection from a Host.
+// CreateConnection:            Creates a Guacamole connection.
+// ListConnections:             Lists all Guacamole connections.
+// GetConnection:               Retrieves a Guacamole connection by identifier.
+// UpdateConnection:            Updates a Guacamole connection.
+// DeleteConnection:            Deletes a Guacamole connection.
+// GetConnectionTree:           Retrieves a connection group with its descendants.
+// CreateConnectionGroup:       Creates a Guacamole connection group.
+// UpdateConnectionGroup:       Updates a Guacamole connection group.
+// DeleteConnectionGroup:       Deletes a Guacamole connection group.
+// ListSharingProfiles:         Lists all Guacamole sharing profiles.
+// GetSharingProfileParameters: Retrieves the parameters of a sharing profile.
+// CreateSharingProfile:        Creates a Guacamole sharing profile.
+// UpdateSharingProfile:        Updates a Guacamole sharing profile.
+// DeleteSharingProfile:        Deletes a Guacamole sharing profile.
+// CreateUser:                  Creates a new Guacamole user.
+// CreateAdminUser:             Creates a new Guacamole admin user.
+// CreateGuacUser:              Creates a Guacamole user with its attributes.
+// GetGuacUser:                 Retrieves a Guacamole user.
+// UpdateGuacUser:              Updates a Guacamole user.
+// ListUsers:                   Lists all Guacamole users.
+// DeleteGuacUser:              Deletes a Guacamole user.
+// SetAdminPassword:            Changes the password of the guacadmin user.
+// SetUserPassword:             Changes the password of any Guacamole user.
+// GetUserPermissions:          Retrieves the permissions of a user.
+// SetUserPermissions:          Grants and revokes permissions of a user.
+// GetUserGroupMembership:      Retrieves the user groups of a user.
+// SetUserGroupMembership:      Adds a user to and removes it from user groups.
+// ListUserGroups:              Lists all Guacamole user groups.
+// CreateUserGroup:             Creates a Guacamole user group.
+// UpdateUserGroup:             Updates a Guacamole user group.
+// DeleteUserGroup:             Deletes a Guacamole user group.
+// GetUserGroupPermissions:     Retrieves the permissions of a user group.
+// SetUserGroupPermissions:     Grants and revokes permissions of a user group.
+//
+// Every method stops with the error of ctx
+// once it is cancelled or its deadline passes.
+type GuacService interface {
+	CreateGuacamoleConnection(ctx context.Context, host Host) error
+	CreateConnection(ctx context.Context, conn *types.GuacConnection) error
+	ListConnections(ctx context.Context) ([]types.GuacConnection, error)
+	GetConnection(ctx context.Context, identifier string) (types.GuacConnection, error)
+	UpdateConnection(ctx context.Context, conn *types.GuacConnection) error
+	DeleteConnection(ctx context.Context, identifier string) error
+	GetConnectionTree(ctx context.Context, identifier string) (types.GuacConnectionGroup, error)
+	CreateConnectionGroup(ctx context.Context, group *types.GuacConnectionGroup) error
+	UpdateConnectionGroup(ctx context.Context, group *types.GuacConnectionGroup) error
+	DeleteConnectionGroup(ctx context.Context, identifier string) error
+	ListSharingProfiles(ctx context.Context) (map[string]SharingProfile, error)
+	GetSharingProfileParameters(ctx context.Context, identifier string) (map[string]string, error)
+	CreateSharingProfile(ctx context.Context, profile *SharingProfile) error
+	UpdateSharingProfile(ctx context.Context, profile *SharingProfile) error
+	DeleteSharingProfile(ctx context.Context, identifier string) error
+	CreateUser(ctx context.Context, user, password string) error
+	CreateAdminUser(ctx context.Context, user, password string) error
+	CreateGuacUser(ctx context.Context, user *types.GuacUser) error
+	GetGuacUser(ctx context.Context, username string) (types.GuacUser, error)
+	UpdateGuacUser(ctx context.Context, user *types.GuacUser) error
+	ListUsers(ctx context.Context) ([]types.GuacUser, error)
+	DeleteGuacUser(ctx context.Context, user string) error
+	SetAdminPassword(ctx context.Context, oldPassword, newPassword string) error
+	SetUserPassword(ctx context.Context, username, oldPassword, newPassword string) error
+	GetUserPermissions(ctx context.Context, username string) (types.GuacPermissionData, error)
+	SetUserPermissions(ctx context.Context, username string, items []types.GuacPermissionItem) error
+	GetUserGroupMembership(ctx context.Context, username string) ([]string, error)
+	SetUserGroupMembership(ctx context.Context, username string, items []types.GuacPermissionItem) error
+	ListUserGroups(ctx context.Context) ([]types.GuacUserGroup, error)
+	CreateUserGroup(ctx context.Context, group *types.GuacUserGroup) error
+	UpdateUserGroup(ctx context.Context, group *types.GuacUserGroup) error
+	DeleteUserGroup(ctx context.Context, identifier string) error
+	GetUserGroupPermissions(ctx context.Context, identifier string) (types.GuacPermissionData, error)
+	SetUserGroupPermissions(ctx context.Context, identifier string, items []types.GuacPermissionItem) error
+}
+
+// objectPath builds the path of an object within a collection.
+func objectPath(collection, identifier string, sub ...string) string {
+	path := collection + "/" + url.PathEscape(identifier)
+	for _, s := range sub {
+		path += "/" + s
+	}
+
+	return path
+}
+
+// CreateGuacamoleConnection establishes a new connection
+// in Guacamole using the provided Host information.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the requests.
+//
+// host: A Host struct containing the necessary information for the connection.
+//
+// **Returns:**
+//
+// error: An error if the connection cannot be created.
+func (c *Client) CreateGuacamoleConnection(ctx context.Context, host Host) error {
+	if err := host.Validate(); err != nil {
+		return err
+	}
+
+	parent, err := c.connectionGroupID(ctx, host.Group)
+	if err != nil {
+		return err
+	}
+
+	conn := host.GuacConnection(parent)
+
+	return c.CreateConnection(ctx, &conn)
+}
+
+// connectionGroupID resolves the identifier of
+// a connection group path, empty for ROOT.
+func (c *Client) connectionGroupID(ctx context.Context, path string) (string, error) {
+	if path == "" || path == RootGroup {
+		return RootGroup, nil
+	}
+
+	tree, err := c.GetConnectionTree(ctx, RootGroup)
+	if err != nil {
+		return "", fmt.Errorf("failed to get connection tree: %w", err)
+	}
+
+	if id, ok := ConnectionGroupIDs(tree)[path]; ok {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("connection group %s does not exist", path)
+}
+
+// ConnectionGroupIDs maps the paths of the connection groups in
+// a tree read from ROOT to their identifiers, so callers creating
+// many connections can resolve their groups with a single read.
+// Both an empty path and ROOT map to the root group.
+//
+// **Parameters:**
+//
+// tree: The connection tree returned by GetConnectionTree for ROOT.
+//
+// **Returns:**
+//
+// map[string]string: The identifiers of the groups by path.
+func ConnectionGroupIDs(tree types.GuacConnectionGroup) map[string]string {
+	ids := map[string]string{"": RootGroup, RootGroup: RootGroup}
+	addGroupIDs(ids, tree, "")
+
+	return ids
+}
+
+func addGroupIDs(ids map[string]string, group types.GuacConnectionGroup, parent string) {
+	for _, child := range group.ChildGroups {
+		childPath := child.Name
+		if parent != "" {
+			childPath = parent + "/" + child.Name
+		}
+		ids[childPath] = child.Identifier
+		addGroupIDs(ids, child, childPath)
+	}
+}
+
+// CreateConnection creates a connection, filling
+// in the identifier Guacamole assigned to it.
+func (c *Client) CreateConnection(ctx context.Context, conn *types.GuacConnection) error {
+	return c.call(ctx, http.MethodPost, connectionsPath, conn, conn)
+}
+
+// ListConnections retrieves all connections
+// the authenticated user can see in Guacamole.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// **Returns:**
+//
+// []types.GuacConnection: The connections found in Guacamole.
+//
+// error: An error if the connections cannot be listed.
+func (c *Client) ListConnections(ctx context.Context) ([]types.GuacConnection, error) {
+	var conns map[string]types.GuacConnection
+	if err := c.call(ctx, http.MethodGet, connectionsPath, nil, &conns); err != nil {
+		return nil, err
+	}
+
+	ret := make([]types.GuacConnection, 0, len(conns))
+	for _, conn := range conns {
+		ret = append(ret, conn)
+	}
+
+	return ret, nil
+}
+
+// GetConnection retrieves a Guacamole connection
+// and its parameters by identifier.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the requests.
+//
+// identifier: A string representing the identifier of the connection.
+//
+// **Returns:**
+//
+// types.GuacConnection: The connection matching the input identifier.
+//
+// error: An error if the connection cannot be retrieved.
+func (c *Client) GetConnection(ctx context.Context, identifier string) (types.GuacConnection, error) {
+	var conn types.GuacConnection
+	if identifier == "" {
+		return conn, errors.New("a connection identifier is required")
+	}
+
+	if err := c.call(ctx, http.MethodGet, objectPath(connectionsPath, identifier), nil, &conn); err != nil {
+		return conn, err
+	}
+
+	if conn.Identifier == "" {
+		return conn, fmt.Errorf("no connection found with identifier %s", identifier)
+	}
+
+	err := c.call(ctx, http.MethodGet, objectPath(connectionsPath, identifier, "parameters"), nil, &conn.Parameters)
+
+	return conn, err
+}
+
+// UpdateConnection replaces the connection with
+// the identifier of conn.
+func (c *Client) UpdateConnection(ctx context.Context, conn *types.GuacConnection) error {
+	return c.call(ctx, http.MethodPut, objectPath(connectionsPath, conn.Identifier), conn, nil)
+}
+
+// DeleteConnection removes a Guacamole connection by identifier.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// identifier: A string representing the identifier of the connection to be deleted.
+//
+// **Returns:**
+//
+// error: An error if the connection cannot be deleted.
+func (c *Client) DeleteConnection(ctx context.Context, identifier string) error {
+	return c.call(ctx, http.MethodDelete, objectPath(connectionsPath, identifier), nil, nil)
+}
+
+// GetConnectionTree retrieves a connection group along with
+// every connection group and connection beneath it.
+func (c *Client) GetConnectionTree(ctx context.Context, identifier string) (types.GuacConnectionGroup, error) {
+	var tree types.GuacConnectionGroup
+	err := c.call(ctx, http.MethodGet, objectPath(connectionGroupsPath, identifier, "tree"), nil, &tree)
+
+	return tree, err
+}
+
+// CreateConnectionGroup creates a connection group, filling
+// in the identifier Guacamole assigned to it.
+func (c *Client) CreateConnectionGroup(ctx context.Context, group *types.GuacConnectionGroup) error {
+	return c.call(ctx, http.MethodPost, connectionGroupsPath, group, group)
+}
+
+// UpdateConnectionGroup replaces the connection group
+// with the identifier of group.
+func (c *Client) UpdateConnectionGroup(ctx context.Context, group *types.GuacConnectionGroup) error {
+	return c.call(ctx, http.MethodPut, objectPath(connectionGroupsPath, group.Identifier), group, nil)
+}
+
+// DeleteConnectionGroup removes a connection group and
+// everything beneath it by identifier.
+func (c *Client) DeleteConnectionGroup(ctx context.Context, identifier string) error {
+	return c.call(ctx, http.MethodDelete, objectPath(connectionGroupsPath, identifier), nil, nil)
+}
+
+// CreateUser creates a new Guacamole user without
+// any system permissions.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// user: A string representing the desired username for the new user.
+//
+// password: A string representing the desired password for the new user.
+//
+// **Returns:**
+//
+// error: An error if the user cannot be created.
+func (c *Client) CreateUser(ctx context.Context, user, password string) error {
+	return c.CreateGuacUser(ctx, &types.GuacUser{Username: user, Password: password})
+}
+
+// CreateAdminUser creates a new admin user in
+// Guacamole with the specified
+// username and password.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the requests.
+//
+// user: A string representing the desired username for the new admin user.
+//
+// password: A string representing the desired password for the new admin user.
+//
+// **Returns:**
+//
+// error: An error if the admin user cannot be created.
+func (c *Client) CreateAdminUser(ctx context.Context, user, password string) error {
+	if err := c.CreateUser(ctx, user, password); err != nil {
+		return err
+	}
+
+	perms := types.SystemPermissions{}
+	items := []types.GuacPermissionItem{
+		SystemPermission(OpAdd, perms.Administer()),
+		SystemPermission(OpAdd, perms.CreateUser()),
+		SystemPermission(OpAdd, perms.CreateConnection()),
+		SystemPermission(OpAdd, perms.CreateConnectionGroup()),
+		SystemPermission(OpAdd, perms.CreateSharingProfile()),
+	}
+
+	return c.SetUserPermissions(ctx, user, items)
+}
+
+// CreateGuacUser creates a user with its attributes.
+func (c *Client) CreateGuacUser(ctx context.Context, user *types.GuacUser) error {
+	return c.call(ctx, http.MethodPost, usersPath, user, user)
+}
+
+// GetGuacUser retrieves a user by username.
+func (c *Client) GetGuacUser(ctx context.Context, username string) (types.GuacUser, error) {
+	var user types.GuacUser
+	err := c.call(ctx, http.MethodGet, objectPath(usersPath, username), nil, &user)
+
+	return user, err
+}
+
+// UpdateGuacUser replaces the attributes of a user, and
+// its password when user.Password is set.
+func (c *Client) UpdateGuacUser(ctx context.Context, user *types.GuacUser) error {
+	return c.call(ctx, http.MethodPut, objectPath(usersPath, user.Username), user, nil)
+}
+
+// ListUsers retrieves all users the authenticated
+// user can see in Guacamole.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// **Returns:**
+//
+// []types.GuacUser: The users found in Guacamole.
+//
+// error: An error if the users cannot be listed.
+func (c *Client) ListUsers(ctx context.Context) ([]types.GuacUser, error) {
+	var users map[string]types.GuacUser
+	if err := c.call(ctx, http.MethodGet, usersPath, nil, &users); err != nil {
+		return nil, err
+	}
+
+	ret := make([]types.GuacUser, 0, len(users))
+	for _, u := range users {
+		ret = append(ret, u)
+	}
+
+	return ret, nil
+}
+
+// DeleteGuacUser removes a specified Guacamole user.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// user: A string representing the username of the Guacamole user to be deleted.
+//
+// **Returns:**
+//
+// error: An error if the specified user cannot be deleted.
+func (c *Client) DeleteGuacUser(ctx context.Context, user string) error {
+	return c.call(ctx, http.MethodDelete, objectPath(usersPath, user), nil, nil)
+}
+
+// SetAdminPassword changes the password of the guacadmin user.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the request.
+//
+// oldPassword: A string representing the current guacadmin password.
+//
+// newPassword: A string representing the new guacadmin password.
+//
+// **Returns:**
+//
+// error: An error if the password cannot be changed.
+func (c *Client) SetAdminPassword(ctx context.Context, oldPassword, newPassword string) error {
+	return c.changePassword(ctx, "guacadmin", oldPassword, newPassword)
+}
+
+// SetUserPassword changes the password of a Guacamole user. With
+// an old password, the change is made the way users change their
+// own password, checking the old password of the user. Without
+// one, an administrator resets the password by updating the user.
+//
+// **Parameters:**
+//
+// ctx: The context cancelling the requests.
+//
+// username: A string representing the user whose password is changed.
+//
+// oldPassword: A string representing the current password, empty for an admin reset.
+//
+// newPassword: A string representing the new password.
+//
+// **Returns:**
+//
+// error: An error if the password cannot be changed.
+func (c *Client) SetUserPassword(ctx context.Context, username, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return errors.New("the new password must not be empty")
+	}
+
+	if oldPassword != "" {
+		return c.changePassword(ctx, username, oldPassword, newPassword)
+	}
+
+	u, err := c.GetGuacUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	u.Password = newPassword
+
+	return c.UpdateGuacUser(ctx, &u)
+}
+
+// changePassword changes the password of a user the way users
+// change their own password. Guacamole checks oldPassword
+// against the user, whoever the session belongs to.
+func (c *Client) changePassword(ctx context.Context, username, oldPassword, newPassword string) error {
+	body := map[string]string{
+		"oldPassword": oldPassword,
+		"newPassword": newPassword,
+	}
+
+	return c.call(ctx, http.MethodPut, objectPath(usersPath, username, "password"), body, nil)
+}
+
+// GetUserPermissions retrieves the permissions granted directly to a user.
+func (c *Client) GetUserPermissions(ctx context.Context, username string) (types.GuacPermissionData, error) {
+	var perms types.GuacPermissionData
+	err := c.call(ctx, http.MethodGet, objectPath(usersPath, username, "permissions"), nil, &perms)
+
+	return perms, err
+}
+
+// SetUserPermissions grants and revokes permissions of a user.
+func (c *Client) SetUserPermissions(ctx context.Context, username string, items []types.GuacPermissionItem) error {
+	return c.call(ctx, http.MethodPatch, objectPath(usersPath, username, "permissions"), items, nil)
+}
+
+// GetUserGroupMembership retrieves the user groups a user belongs to.
+func (c *Client) GetUserGroupMembership(ctx context.Context, username string) ([]string, error) {
+	var groups []string
+	err := c.call(ctx, http.MethodGet, objectPath(usersPath, username, "userGroups"), nil, &groups)
+
+	return groups, err
+}
+
+// SetUserGroupMembership adds a user to and removes it from user groups.
+func (c *Client) SetUserGroupMembership(ctx context.Context, username string, items []types.GuacPermissionItem) error {
+	return c.call(ctx, http.MethodPatch, objectPath(usersPath, username, "userGroups"), items, nil)
+}
+
+// ListUserGroups retrieves all user groups the
+// authenticated user can see in Guacamole.
+func (c *Client) ListUserGroups(ctx context.Context) ([]types.GuacUserGroup, error) {
+	var groups map[string]types.GuacUserGroup
+	if err := c.call(ctx, http.MethodGet, userGroupsPath, nil, &groups); err != nil {
+		return nil, err
+	}
+
+	ret := make([]types.GuacUserGroup, 0, len(groups))
+	for _, g := range groups {
+		ret = append(ret, g)
+	}
+
+	return ret, nil
+}
+
+// CreateUserGroup creates a user group.
+func (c *Client) CreateUserGroup(ctx context.Context, group *types.GuacUserGroup) error {
+	return c.call(ctx, http.MethodPost, userGroupsPath, group, group)
+}
+
+// UpdateUserGroup replaces the attributes of a user group.
+func (c *Client) UpdateUserGroup(ctx context.Context, group *types.GuacUserGroup) error {
+	return c.call(ctx, http.MethodPut, objectPath(userGroupsPath, group.Identifier), group, nil)
+}
+
+// DeleteUserGroup removes a user group by identifier.
+func (c *Client) DeleteUserGroup(ctx context.Context, identifier string) error {
+	return c.call(ctx, http.MethodDelete, objectPath(userGroupsPath, identifier), nil, nil)
+}
+
+// GetUserGroupPermissions retrieves the permissions granted to a user group.
+func (c *Client) GetUserGroupPermissions(ctx context.Context, identifier string) (types.GuacPermissionData, error) {
+	var perms types.GuacPermissionData
+	err := c.call(ctx, http.MethodGet, objectPath(userGroupsPath, identifier, "permissions"), nil, &perms)
+
+	return perms, err
+}
+
+// SetUserGroupPermissions grants and revokes permissions of a user group.
+func (c *Client) SetUserGroupPermissions(ctx context.Context, identifier string, items []types.GuacPermissionItem) error {
+	return c.call(ctx, http.MethodPatch, objectPath(userGroupsPath, identifier, "permissions"), items, nil)
+}
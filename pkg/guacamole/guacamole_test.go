@@ -0,0 +1,677 @@
+package guacamole_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+type MockGuacService struct {
+	mock.Mock
+}
+
+var (
+	mockService                       = new(MockGuacService)
+	_           guacamole.GuacService = mockService
+)
+
+func (m *MockGuacService) CreateGuacamoleConnection(_ context.Context, host guacamole.Host) error {
+	args := m.Called(host)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) CreateConnection(_ context.Context, conn *types.GuacConnection) error {
+	args := m.Called(conn)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) ListConnections(_ context.Context) ([]types.GuacConnection, error) {
+	args := m.Called()
+	return args.Get(0).([]types.GuacConnection), args.Error(1)
+}
+
+func (m *MockGuacService) GetConnection(_ context.Context, identifier string) (types.GuacConnection, error) {
+	args := m.Called(identifier)
+	return args.Get(0).(types.GuacConnection), args.Error(1)
+}
+
+func (m *MockGuacService) UpdateConnection(_ context.Context, conn *types.GuacConnection) error {
+	args := m.Called(conn)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) DeleteConnection(_ context.Context, identifier string) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) GetConnectionTree(_ context.Context, identifier string) (types.GuacConnectionGroup, error) {
+	args := m.Called(identifier)
+	return args.Get(0).(types.GuacConnectionGroup), args.Error(1)
+}
+
+func (m *MockGuacService) CreateConnectionGroup(_ context.Context, group *types.GuacConnectionGroup) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) UpdateConnectionGroup(_ context.Context, group *types.GuacConnectionGroup) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) DeleteConnectionGroup(_ context.Context, identifier string) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) ListSharingProfiles(_ context.Context) (map[string]guacamole.SharingProfile, error) {
+	args := m.Called()
+	return args.Get(0).(map[string]guacamole.SharingProfile), args.Error(1)
+}
+
+func (m *MockGuacService) GetSharingProfileParameters(_ context.Context, identifier string) (map[string]string, error) {
+	args := m.Called(identifier)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockGuacService) CreateSharingProfile(_ context.Context, profile *guacamole.SharingProfile) error {
+	args := m.Called(profile)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) UpdateSharingProfile(_ context.Context, profile *guacamole.SharingProfile) error {
+	args := m.Called(profile)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) DeleteSharingProfile(_ context.Context, identifier string) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) CreateUser(_ context.Context, user, password string) error {
+	args := m.Called(user, password)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) CreateAdminUser(_ context.Context, user, password string) error {
+	args := m.Called(user, password)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) CreateGuacUser(_ context.Context, user *types.GuacUser) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) GetGuacUser(_ context.Context, username string) (types.GuacUser, error) {
+	args := m.Called(username)
+	return args.Get(0).(types.GuacUser), args.Error(1)
+}
+
+func (m *MockGuacService) UpdateGuacUser(_ context.Context, user *types.GuacUser) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) ListUsers(_ context.Context) ([]types.GuacUser, error) {
+	args := m.Called()
+	return args.Get(0).([]types.GuacUser), args.Error(1)
+}
+
+func (m *MockGuacService) DeleteGuacUser(_ context.Context, user string) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) SetAdminPassword(_ context.Context, oldPassword, newPassword string) error {
+	args := m.Called(oldPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) SetUserPassword(_ context.Context, username, oldPassword, newPassword string) error {
+	args := m.Called(username, oldPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) GetUserPermissions(_ context.Context, username string) (types.GuacPermissionData, error) {
+	args := m.Called(username)
+	return args.Get(0).(types.GuacPermissionData), args.Error(1)
+}
+
+func (m *MockGuacService) SetUserPermissions(_ context.Context, username string, items []types.GuacPermissionItem) error {
+	args := m.Called(username, items)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) GetUserGroupMembership(_ context.Context, username string) ([]string, error) {
+	args := m.Called(username)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockGuacService) SetUserGroupMembership(_ context.Context, username string, items []types.GuacPermissionItem) error {
+	args := m.Called(username, items)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) ListUserGroups(_ context.Context) ([]types.GuacUserGroup, error) {
+	args := m.Called()
+	return args.Get(0).([]types.GuacUserGroup), args.Error(1)
+}
+
+func (m *MockGuacService) CreateUserGroup(_ context.Context, group *types.GuacUserGroup) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) UpdateUserGroup(_ context.Context, group *types.GuacUserGroup) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) DeleteUserGroup(_ context.Context, identifier string) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *MockGuacService) GetUserGroupPermissions(_ context.Context, identifier string) (types.GuacPermissionData, error) {
+	args := m.Called(identifier)
+	return args.Get(0).(types.GuacPermissionData), args.Error(1)
+}
+
+func (m *MockGuacService) SetUserGroupPermissions(_ context.Context, identifier string, items []types.GuacPermissionItem) error {
+	args := m.Called(identifier, items)
+	return args.Error(0)
+}
+
+func TestCreateGuacamoleConnection(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      guacamole.Host
+		expectErr bool
+	}{
+		{
+			name: "Valid VNC host",
+			host: guacamole.Host{
+				Name:     "Example",
+				Protocol: guacamole.ProtocolVNC,
+				IP:       "guacamole.techvomit.xyz",
+				Port:     5900,
+				Password: "guacadmin",
+			},
+			expectErr: false,
+		},
+		{
+			name: "Invalid host",
+			host: guacamole.Host{
+				Name:     "Invalid",
+				Protocol: guacamole.ProtocolVNC,
+				IP:       "192.168.1.300", // Invalid IP
+				Port:     5900,
+				Password: "password",
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectErr {
+				mockService.On("CreateGuacamoleConnection", tc.host).Return(fmt.Errorf("some error"))
+			} else {
+				mockService.On("CreateGuacamoleConnection", tc.host).Return(nil)
+			}
+
+			err := mockService.CreateGuacamoleConnection(context.Background(), tc.host)
+
+			mockService.AssertExpectations(t)
+
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateGuacamoleConnectionRDP(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      guacamole.Host
+		expectErr bool
+		expected  types.GuacConnection
+	}{
+		{
+			name: "Valid RDP host",
+			host: guacamole.Host{
+				Name:     "Windows",
+				Protocol: guacamole.ProtocolRDP,
+				IP:       "10.0.0.10",
+				Port:     3390,
+				Username: "Administrator",
+				Password: "password",
+				RDP: guacamole.RDPSettings{
+					Domain:     "LAB",
+					Security:   "nla",
+					IgnoreCert: true,
+				},
+			},
+			expected: types.GuacConnection{
+				Name:             "Windows",
+				ParentIdentifier: guacamole.RootGroup,
+				Protocol:         "rdp",
+				Parameters: types.GuacConnectionParameters{
+					Hostname:   "10.0.0.10",
+					Port:       "3390",
+					Username:   "Administrator",
+					Password:   "password",
+					Domain:     "LAB",
+					Security:   "nla",
+					IgnoreCert: "true",
+				},
+				Attributes: types.GuacConnectionAttributes{
+					MaxConnections:        "2",
+					MaxConnectionsPerUser: "1",
+				},
+			},
+		},
+		{
+			name: "Invalid RDP security mode",
+			host: guacamole.Host{
+				Name:     "Windows",
+				Protocol: guacamole.ProtocolRDP,
+				IP:       "10.0.0.10",
+				RDP:      guacamole.RDPSettings{Security: "kerberos"},
+			},
+			expectErr: true,
+		},
+	}
+
+	var posted []types.GuacConnection
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/session/data/postgresql/connections", func(w http.ResponseWriter, r *http.Request) {
+		var conn types.GuacConnection
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&conn))
+		posted = append(posted, conn)
+		conn.Identifier = "1"
+		_ = json.NewEncoder(w).Encode(conn)
+	})
+	client := newLoggedInClient(t, mux, "guacadmin")
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			posted = nil
+			err := client.CreateGuacamoleConnection(context.Background(), tc.host)
+			if tc.expectErr {
+				require.Error(t, err)
+				require.Empty(t, posted)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []types.GuacConnection{tc.expected}, posted)
+		})
+	}
+}
+
+func TestCreateAdminUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		password  string
+		expectErr bool
+	}{
+		{
+			name:      "Valid user and password",
+			user:      "admin",
+			password:  "secure_password",
+			expectErr: false,
+		},
+		{
+			name:      "Invalid user",
+			user:      "", // Empty user
+			password:  "secure_password",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectErr {
+				mockService.On("CreateAdminUser", tc.user, tc.password).Return(fmt.Errorf("some error"))
+			} else {
+				mockService.On("CreateAdminUser", tc.user, tc.password).Return(nil)
+			}
+
+			err := mockService.CreateAdminUser(context.Background(), tc.user, tc.password)
+
+			mockService.AssertExpectations(t)
+
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetConnection(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		expectErr  bool
+		errIs      error
+	}{
+		{
+			name:       "Existing connection",
+			identifier: "1",
+		},
+		{
+			name:       "Missing connection",
+			identifier: "404",
+			expectErr:  true,
+			errIs:      guacamole.ErrNotFound,
+		},
+		{
+			name:       "Empty identifier",
+			identifier: "",
+			expectErr:  true,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/session/data/postgresql/connections/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token", r.Header.Get("Guacamole-Token"))
+		if r.PathValue("id") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type":"NOT_FOUND","message":"No such connection."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"identifier":"1","name":"web01","protocol":"ssh","parentIdentifier":"ROOT"}`))
+	})
+	mux.HandleFunc("GET /api/session/data/postgresql/connections/1/parameters", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"hostname":"10.0.0.5","port":"22"}`))
+	})
+	client := newLoggedInClient(t, mux, "guacadmin")
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := client.GetConnection(context.Background(), tc.identifier)
+			if tc.expectErr {
+				require.Error(t, err)
+				if tc.errIs != nil {
+					require.ErrorIs(t, err, tc.errIs)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.identifier, conn.Identifier)
+			require.Equal(t, "web01", conn.Name)
+			require.Equal(t, "10.0.0.5", conn.Parameters.Hostname)
+			require.Equal(t, "22", conn.Parameters.Port)
+		})
+	}
+}
+
+func TestDeleteConnection(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		expectErr  bool
+		errIs      error
+	}{
+		{
+			name:       "Existing connection",
+			identifier: "1",
+		},
+		{
+			name:       "Missing connection",
+			identifier: "404",
+			expectErr:  true,
+			errIs:      guacamole.ErrNotFound,
+		},
+	}
+
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/session/data/postgresql/connections/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token", r.Header.Get("Guacamole-Token"))
+		if r.PathValue("id") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type":"NOT_FOUND","message":"No such connection."}`))
+			return
+		}
+		deleted = append(deleted, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client := newLoggedInClient(t, mux, "guacadmin")
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			deleted = nil
+			err := client.DeleteConnection(context.Background(), tc.identifier)
+			if tc.expectErr {
+				require.Error(t, err)
+				require.ErrorIs(t, err, tc.errIs)
+				require.Empty(t, deleted)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []string{tc.identifier}, deleted)
+		})
+	}
+}
+
+func TestCreateGuacUserDuplicate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/session/data/postgresql/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"BAD_REQUEST","message":"User \"bob\" already exists."}`))
+	})
+	client := newLoggedInClient(t, mux, "guacadmin")
+
+	err := client.CreateGuacUser(context.Background(), &types.GuacUser{Username: "bob"})
+	require.ErrorIs(t, err, guacamole.ErrConflict)
+	require.ErrorContains(t, err, `User "bob" already exists.`)
+}
+
+func TestDeleteGuacUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		expectErr bool
+	}{
+		{
+			name:      "Valid user",
+			user:      "user_to_delete",
+			expectErr: false,
+		},
+		{
+			name:      "Invalid user",
+			user:      "", // Empty user
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectErr {
+				mockService.On("DeleteGuacUser", tc.user).Return(fmt.Errorf("some error"))
+			} else {
+				mockService.On("DeleteGuacUser", tc.user).Return(nil)
+			}
+
+			err := mockService.DeleteGuacUser(context.Background(), tc.user)
+
+			mockService.AssertExpectations(t)
+
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// newPasswordServer serves the user endpoints involved in
+// password changes, recording each request it receives as
+// its method, path and JSON body.
+func newPasswordServer(t *testing.T, username string) (*guacamole.Client, *[]string) {
+	t.Helper()
+
+	var requests []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "token", r.Header.Get("Guacamole-Token"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"username":"` + r.PathValue("user") + `","attributes":{"guac-full-name":"Bob"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/session/data/postgresql/users/{user}", record)
+	mux.HandleFunc("PUT /api/session/data/postgresql/users/{user}", record)
+	mux.HandleFunc("PUT /api/session/data/postgresql/users/{user}/password", record)
+
+	return newLoggedInClient(t, mux, username), &requests
+}
+
+func TestSetAdminPassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		loginAs     string
+		oldPassword string
+		newPassword string
+		expectErr   bool
+		expected    []string
+	}{
+		{
+			name:        "Logged in as guacadmin",
+			loginAs:     "guacadmin",
+			oldPassword: "guacadmin",
+			newPassword: "s1cknewpassword",
+			expected: []string{
+				`PUT /api/session/data/postgresql/users/guacadmin/password {"newPassword":"s1cknewpassword","oldPassword":"guacadmin"}`,
+			},
+		},
+		{
+			name:        "Logged in as another admin",
+			loginAs:     "alice",
+			oldPassword: "guacadmin",
+			newPassword: "s1cknewpassword",
+			expected: []string{
+				`PUT /api/session/data/postgresql/users/guacadmin/password {"newPassword":"s1cknewpassword","oldPassword":"guacadmin"}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, requests := newPasswordServer(t, tc.loginAs)
+
+			err := client.SetAdminPassword(context.Background(), tc.oldPassword, tc.newPassword)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expected, *requests)
+		})
+	}
+}
+
+func TestSetUserPassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		oldPassword string
+		newPassword string
+		expectErr   bool
+		expected    []string
+	}{
+		{
+			name:        "Self-service change",
+			username:    "alice",
+			oldPassword: "0ldpassword",
+			newPassword: "s1cknewpassword",
+			expected: []string{
+				`PUT /api/session/data/postgresql/users/alice/password {"newPassword":"s1cknewpassword","oldPassword":"0ldpassword"}`,
+			},
+		},
+		{
+			name:        "Admin reset",
+			username:    "bob",
+			newPassword: "s1cknewpassword",
+			expected: []string{
+				`GET /api/session/data/postgresql/users/bob`,
+				`PUT /api/session/data/postgresql/users/bob {"username":"bob","password":"s1cknewpassword","attributes":{"guac-full-name":"Bob"}}`,
+			},
+		},
+		{
+			name:        "Change of another user with the old password",
+			username:    "bob",
+			oldPassword: "0ldpassword",
+			newPassword: "s1cknewpassword",
+			expected: []string{
+				`PUT /api/session/data/postgresql/users/bob/password {"newPassword":"s1cknewpassword","oldPassword":"0ldpassword"}`,
+			},
+		},
+		{
+			name:        "Empty new password",
+			username:    "carol",
+			newPassword: "",
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, requests := newPasswordServer(t, "alice")
+
+			err := client.SetUserPassword(context.Background(), tc.username, tc.oldPassword, tc.newPassword)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expected, *requests)
+		})
+	}
+}
+
+func TestConnectionGroupIDs(t *testing.T) {
+	tree := types.GuacConnectionGroup{
+		Identifier: guacamole.RootGroup,
+		ChildGroups: []types.GuacConnectionGroup{{
+			Identifier:  "1",
+			Name:        "lab",
+			ChildGroups: []types.GuacConnectionGroup{{Identifier: "2", Name: "linux"}},
+		}},
+	}
+
+	require.Equal(t, map[string]string{
+		"":                  guacamole.RootGroup,
+		guacamole.RootGroup: guacamole.RootGroup,
+		"lab":               "1",
+		"lab/linux":         "2",
+	}, guacamole.ConnectionGroupIDs(tree))
+}
@@ -0,0 +1,380 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package guacamole
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+const (
+	// ProtocolVNC is the Guacamole protocol name for VNC connections.
+	ProtocolVNC = "vnc"
+	// ProtocolRDP is the Guacamole protocol name for RDP connections.
+	ProtocolRDP = "rdp"
+	// ProtocolSSH is the Guacamole protocol name for SSH connections.
+	ProtocolSSH = "ssh"
+	// ProtocolTelnet is the Guacamole protocol name for Telnet connections.
+	ProtocolTelnet = "telnet"
+	// ProtocolKubernetes is the Guacamole protocol name for Kubernetes pod connections.
+	ProtocolKubernetes = "kubernetes"
+)
+
+// DefaultPort returns the standard port of a protocol,
+// used when a Host does not provide one.
+//
+// **Parameters:**
+//
+// protocol: The Guacamole protocol name.
+//
+// **Returns:**
+//
+// int: The standard port of the protocol, 0 if it is unknown.
+func DefaultPort(protocol string) int {
+	switch protocol {
+	case ProtocolVNC:
+		return 5900
+	case ProtocolRDP:
+		return 3389
+	case ProtocolSSH:
+		return 22
+	case ProtocolTelnet:
+		return 23
+	case ProtocolKubernetes:
+		return 8080
+	default:
+		return 0
+	}
+}
+
+// Host represents the parameters used to establish
+// a new connection in Guacamole, independent of protocol.
+//
+// **Attributes:**
+//
+// Name:       A string representing the name of the connection.
+// Group:      A string representing the path of the connection group holding the connection, empty for ROOT.
+// Protocol:   A string representing the protocol used by the connection (vnc, rdp, ssh, telnet or kubernetes).
+// IP:         A string representing the hostname or IP address of the host.
+// Port:       An integer representing the port to connect to on the host.
+// Username:   A string representing the username for the host (not used by VNC or kubernetes).
+// Password:   A string representing the password for the host.
+// RDP:        RDP specific settings, ignored for other protocols.
+// SSH:        SSH specific settings, ignored for other protocols.
+// Kubernetes: Kubernetes specific settings, ignored for other protocols.
+// Terminal:   Display settings for text based protocols (ssh, telnet and kubernetes).
+type Host struct {
+	Name       string
+	Group      string
+	Protocol   string
+	IP         string
+	Port       int
+	Username   string
+	Password   string
+	RDP        RDPSettings
+	SSH        SSHSettings
+	Kubernetes KubernetesSettings
+	Terminal   TerminalSettings
+}
+
+// RDPSettings represents the RDP specific parameters
+// of a Guacamole connection.
+//
+// **Attributes:**
+//
+// Domain:          The domain to authenticate against.
+// Security:        The security mode (any, nla, rdp, tls or vmconnect).
+// IgnoreCert:      Ignore the certificate presented by the server.
+// ResizeMethod:    The method used to resize the display (display-update or reconnect).
+// EnableDrive:     Enable drive redirection.
+// DriveName:       The name of the redirected drive.
+// DrivePath:       The path on the guacd server backing the redirected drive.
+// GatewayHostname: The hostname of the remote desktop gateway.
+// GatewayPort:     The port of the remote desktop gateway.
+// GatewayUsername: The username used to authenticate with the gateway.
+// GatewayPassword: The password used to authenticate with the gateway.
+// GatewayDomain:   The domain used to authenticate with the gateway.
+type RDPSettings struct {
+	Domain          string
+	Security        string
+	IgnoreCert      bool
+	ResizeMethod    string
+	EnableDrive     bool
+	DriveName       string
+	DrivePath       string
+	GatewayHostname string
+	GatewayPort     int
+	GatewayUsername string
+	GatewayPassword string
+	GatewayDomain   string
+}
+
+// SSHSettings represents the SSH specific parameters
+// of a Guacamole connection.
+//
+// **Attributes:**
+//
+// PrivateKey: The PEM encoded private key used to authenticate.
+// Passphrase: The passphrase protecting the private key.
+// HostKey:    The known public host key of the server.
+// EnableSFTP: Enable file transfer over SFTP.
+type SSHSettings struct {
+	PrivateKey string
+	Passphrase string
+	HostKey    string
+	EnableSFTP bool
+}
+
+// KubernetesSettings represents the kubernetes specific
+// parameters of a Guacamole connection. The Host IP and
+// Port point at the Kubernetes API server.
+//
+// **Attributes:**
+//
+// Namespace:  The namespace of the pod.
+// Pod:        The name of the pod to attach to.
+// Container:  The container within the pod, defaults to the first container.
+// UseSSL:     Connect to the API server over TLS.
+// IgnoreCert: Ignore the certificate presented by the API server.
+// CACert:     The PEM encoded certificate authority of the API server.
+// ClientCert: The PEM encoded client certificate used to authenticate.
+// ClientKey:  The PEM encoded client key used to authenticate.
+type KubernetesSettings struct {
+	Namespace  string
+	Pod        string
+	Container  string
+	UseSSL     bool
+	IgnoreCert bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// TerminalSettings represents the display parameters
+// shared by the text based protocols.
+//
+// **Attributes:**
+//
+// ColorScheme: The color scheme of the terminal.
+// FontName:    The name of the font used by the terminal.
+// FontSize:    The size of the font used by the terminal, in points.
+type TerminalSettings struct {
+	ColorScheme string
+	FontName    string
+	FontSize    int
+}
+
+// Validate checks that the Host has everything
+// required to create a connection for its protocol.
+//
+// **Returns:**
+//
+// error: An error describing every invalid field, if any.
+func (h Host) Validate() error {
+	var errs []error
+
+	if h.Name == "" {
+		errs = append(errs, errors.New("a connection name is required"))
+	}
+	if h.IP == "" {
+		errs = append(errs, errors.New("a hostname or IP address is required"))
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", h.Port))
+	}
+
+	switch h.Protocol {
+	case ProtocolVNC:
+		if h.Password == "" {
+			errs = append(errs, errors.New("a VNC password is required"))
+		}
+	case ProtocolRDP:
+		errs = append(errs, h.RDP.validate()...)
+	case ProtocolSSH:
+		if h.SSH.Passphrase != "" && h.SSH.PrivateKey == "" {
+			errs = append(errs, errors.New("an SSH passphrase requires a private key"))
+		}
+		errs = append(errs, h.Terminal.validate()...)
+	case ProtocolTelnet:
+		errs = append(errs, h.Terminal.validate()...)
+	case ProtocolKubernetes:
+		if h.Kubernetes.Pod == "" {
+			errs = append(errs, errors.New("a pod is required for kubernetes connections"))
+		}
+		errs = append(errs, h.Terminal.validate()...)
+	default:
+		errs = append(errs, fmt.Errorf("unsupported protocol %q", h.Protocol))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r RDPSettings) validate() []error {
+	var errs []error
+	params := types.GuacConnectionParameters{}
+
+	if r.Security != "" && !types.StrSlice(params.ValidSecurityModes()).Has(r.Security) {
+		errs = append(errs, fmt.Errorf("invalid RDP security mode %q, must be one of %v",
+			r.Security, params.ValidSecurityModes()))
+	}
+	if r.ResizeMethod != "" && !types.StrSlice(params.ValidResizeMethods()).Has(r.ResizeMethod) {
+		errs = append(errs, fmt.Errorf("invalid RDP resize method %q, must be one of %v",
+			r.ResizeMethod, params.ValidResizeMethods()))
+	}
+	if r.GatewayPort < 0 || r.GatewayPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid RDP gateway port %d", r.GatewayPort))
+	}
+
+	return errs
+}
+
+func (t TerminalSettings) validate() []error {
+	var errs []error
+	params := types.GuacConnectionParameters{}
+
+	if t.ColorScheme != "" && !types.StrSlice(params.ValidColorSchemes()).Has(t.ColorScheme) {
+		errs = append(errs, fmt.Errorf("invalid color scheme %q, must be one of %v",
+			t.ColorScheme, params.ValidColorSchemes()))
+	}
+	if t.FontSize != 0 && !types.StrSlice(params.ValidFontSizes()).Has(strconv.Itoa(t.FontSize)) {
+		errs = append(errs, fmt.Errorf("invalid font size %d, must be one of %v",
+			t.FontSize, params.ValidFontSizes()))
+	}
+
+	return errs
+}
+
+// GuacConnection converts the Host into a Guacamole
+// connection in the connection group parentIdentifier.
+//
+// **Parameters:**
+//
+// parentIdentifier: The identifier of the connection group holding the connection.
+//
+// **Returns:**
+//
+// types.GuacConnection: The connection to create.
+func (h Host) GuacConnection(parentIdentifier string) types.GuacConnection {
+	return types.GuacConnection{
+		Name:             h.Name,
+		ParentIdentifier: parentIdentifier,
+		Protocol:         h.Protocol,
+		Attributes: types.GuacConnectionAttributes{
+			MaxConnections:        "2",
+			MaxConnectionsPerUser: "1",
+		},
+		Parameters: h.ConnectionParameters(),
+	}
+}
+
+// ConnectionParameters converts the Host into the
+// Guacamole connection parameters for its protocol.
+//
+// **Returns:**
+//
+// types.GuacConnectionParameters: The parameters for the connection.
+func (h Host) ConnectionParameters() types.GuacConnectionParameters {
+	params := types.GuacConnectionParameters{
+		Hostname: h.IP,
+		Password: h.Password,
+	}
+	if h.Port != 0 {
+		params.Port = strconv.Itoa(h.Port)
+	}
+
+	switch h.Protocol {
+	case ProtocolRDP:
+		params.Username = h.Username
+		h.RDP.apply(&params)
+	case ProtocolSSH:
+		params.Username = h.Username
+		h.SSH.apply(&params)
+		h.Terminal.apply(&params)
+	case ProtocolTelnet:
+		params.Username = h.Username
+		h.Terminal.apply(&params)
+	case ProtocolKubernetes:
+		params.Password = ""
+		h.Kubernetes.apply(&params)
+		h.Terminal.apply(&params)
+	}
+
+	return params
+}
+
+func (r RDPSettings) apply(params *types.GuacConnectionParameters) {
+	params.Domain = r.Domain
+	params.Security = r.Security
+	params.IgnoreCert = boolParam(r.IgnoreCert)
+	params.ResizeMethod = r.ResizeMethod
+	params.EnableDrive = boolParam(r.EnableDrive)
+	params.DriveName = r.DriveName
+	params.DrivePath = r.DrivePath
+	params.CreateDrivePath = boolParam(r.EnableDrive && r.DrivePath != "")
+	params.GatewayHostname = r.GatewayHostname
+	params.GatewayUsername = r.GatewayUsername
+	params.GatewayPassword = r.GatewayPassword
+	params.GatewayDomain = r.GatewayDomain
+	if r.GatewayPort != 0 {
+		params.GatewayPort = strconv.Itoa(r.GatewayPort)
+	}
+}
+
+func (s SSHSettings) apply(params *types.GuacConnectionParameters) {
+	params.PrivateKey = s.PrivateKey
+	params.Passphrase = s.Passphrase
+	params.PublicHostKey = s.HostKey
+	params.EnableSFTP = boolParam(s.EnableSFTP)
+}
+
+func (k KubernetesSettings) apply(params *types.GuacConnectionParameters) {
+	params.Namespace = k.Namespace
+	params.Pod = k.Pod
+	params.Container = k.Container
+	params.UseSSL = boolParam(k.UseSSL)
+	params.IgnoreCert = boolParam(k.IgnoreCert)
+	params.CACert = k.CACert
+	params.ClientCert = k.ClientCert
+	params.ClientKey = k.ClientKey
+}
+
+func (t TerminalSettings) apply(params *types.GuacConnectionParameters) {
+	params.ColorScheme = t.ColorScheme
+	params.FontName = t.FontName
+	if t.FontSize != 0 {
+		params.FontSize = strconv.Itoa(t.FontSize)
+	}
+}
+
+// boolParam renders a boolean the way Guacamole
+// expects it in connection parameters.
+func boolParam(b bool) string {
+	if b {
+		return "true"
+	}
+
+	return ""
+}
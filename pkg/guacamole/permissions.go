@@ -0,0 +1,70 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package guacamole
+
+import (
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// PatchOp is the operation of a permission patch.
+type PatchOp string
+
+const (
+	// OpAdd grants a permission or adds a membership.
+	OpAdd PatchOp = "add"
+	// OpRemove revokes a permission or removes a membership.
+	OpRemove PatchOp = "remove"
+)
+
+// SystemPermission builds the operation granting
+// or revoking a system permission.
+func SystemPermission(op PatchOp, permission string) types.GuacPermissionItem {
+	return permissionItem(op, "/systemPermissions", permission)
+}
+
+// ConnectionPermission builds the operation granting
+// or revoking read access to a connection.
+func ConnectionPermission(op PatchOp, identifier string) types.GuacPermissionItem {
+	return permissionItem(op, "/connectionPermissions/"+identifier, "READ")
+}
+
+// ConnectionGroupPermission builds the operation granting
+// or revoking read access to a connection group.
+func ConnectionGroupPermission(op PatchOp, identifier string) types.GuacPermissionItem {
+	return permissionItem(op, "/connectionGroupPermissions/"+identifier, "READ")
+}
+
+// SharingProfilePermission builds the operation granting
+// or revoking read access to a sharing profile.
+func SharingProfilePermission(op PatchOp, identifier string) types.GuacPermissionItem {
+	return permissionItem(op, "/sharingProfilePermissions/"+identifier, "READ")
+}
+
+// GroupMembership builds the operation adding a user
+// to or removing a user from a user group.
+func GroupMembership(op PatchOp, group string) types.GuacPermissionItem {
+	return permissionItem(op, "/", group)
+}
+
+func permissionItem(op PatchOp, path, value string) types.GuacPermissionItem {
+	return types.GuacPermissionItem{Op: string(op), Path: path, Value: value}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package guacamole
+
+import (
+	"context"
+	"net/http"
+)
+
+const sharingProfilesPath = "sharingProfiles"
+
+// SharingProfile is the REST representation of a Guacamole
+// sharing profile, which the API client types do not cover.
+//
+// **Attributes:**
+//
+// Identifier: The identifier Guacamole assigned to the sharing profile.
+// Name: The name of the sharing profile.
+// PrimaryConnectionIdentifier: The identifier of the connection being shared.
+// Parameters: The sharing parameters, only sent when creating or updating.
+// Attributes: The attributes of the sharing profile.
+type SharingProfile struct {
+	Identifier                  string            `json:"identifier,omitempty"`
+	Name                        string            `json:"name"`
+	PrimaryConnectionIdentifier string            `json:"primaryConnectionIdentifier"`
+	Parameters                  map[string]string `json:"parameters,omitempty"`
+	Attributes                  map[string]string `json:"attributes"`
+}
+
+// ListSharingProfiles returns every sharing profile
+// visible to the authenticated user, keyed by identifier.
+func (c *Client) ListSharingProfiles(ctx context.Context) (map[string]SharingProfile, error) {
+	var profiles map[string]SharingProfile
+	err := c.call(ctx, http.MethodGet, sharingProfilesPath, nil, &profiles)
+
+	return profiles, err
+}
+
+// GetSharingProfileParameters returns the parameters of
+// a sharing profile, which listing does not include.
+func (c *Client) GetSharingProfileParameters(ctx context.Context, identifier string) (map[string]string, error) {
+	var params map[string]string
+	err := c.call(ctx, http.MethodGet, objectPath(sharingProfilesPath, identifier, "parameters"), nil, &params)
+
+	return params, err
+}
+
+// CreateSharingProfile creates a sharing profile,
+// filling in the identifier Guacamole assigned to it.
+func (c *Client) CreateSharingProfile(ctx context.Context, profile *SharingProfile) error {
+	return c.call(ctx, http.MethodPost, sharingProfilesPath, profile, profile)
+}
+
+// UpdateSharingProfile replaces the sharing profile with
+// the identifier of profile.
+func (c *Client) UpdateSharingProfile(ctx context.Context, profile *SharingProfile) error {
+	return c.call(ctx, http.MethodPut, objectPath(sharingProfilesPath, profile.Identifier), profile, nil)
+}
+
+// DeleteSharingProfile removes a sharing profile by identifier.
+func (c *Client) DeleteSharingProfile(ctx context.Context, identifier string) error {
+	return c.call(ctx, http.MethodDelete, objectPath(sharingProfilesPath, identifier), nil, nil)
+}
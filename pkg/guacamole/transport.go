@@ -0,0 +1,252 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package guacamole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how requests Guacamole could
+// not answer are retried.
+//
+// **Attributes:**
+//
+// Attempts: How many times to retry a request, 0 to never retry.
+// MinBackoff: How long to wait before the first retry.
+// MaxBackoff: The longest to wait between retries.
+type RetryPolicy struct {
+	Attempts   int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// newHTTPClient builds the HTTP client of a Client, which
+// applies the TLS, proxy, header, retry and timeout settings
+// of cfg to every request.
+func newHTTPClient(cfg Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg.TLS
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	headers := http.Header{}
+	for name, value := range cfg.Headers {
+		headers.Set(name, value)
+	}
+	if cfg.UserAgent != "" {
+		headers.Set("User-Agent", cfg.UserAgent)
+	}
+
+	return &http.Client{
+		Transport: &headerTransport{
+			base:    &retryTransport{base: transport, retry: cfg.Retry, timeout: cfg.Timeout, logf: cfg.logf},
+			headers: headers,
+		},
+	}, nil
+}
+
+// headerTransport adds static headers to every request it sends.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+// RoundTrip sends a copy of the request carrying the headers.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// retryTransport retries requests Guacamole could not answer
+// with jittered exponential backoff, and bounds each attempt
+// with a timeout.
+//
+// **Attributes:**
+//
+// base: The transport sending each attempt.
+// retry: How often and how long to back off.
+// timeout: How long each attempt may take, 0 for no limit.
+// logf: Logs each retry.
+type retryTransport struct {
+	base    http.RoundTripper
+	retry   RetryPolicy
+	timeout time.Duration
+	logf    func(format string, args ...interface{})
+}
+
+// RoundTrip sends a request, retrying it when the connection is
+// refused or Guacamole answers 429 or 503 with a Retry-After
+// header. Reset connections, timeouts and other server errors
+// are only retried for idempotent methods, as the request may
+// have been applied before the failure.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.send(req)
+		if attempt >= t.retry.Attempts || !retryable(req, resp, err) || !rewind(req) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			t.logf("Guacamole answered %s %s with %s, retrying in %s", req.Method, req.URL.Path, resp.Status, wait)
+			drain(resp)
+		} else {
+			t.logf("Failed to reach Guacamole for %s %s: %v, retrying in %s", req.Method, req.URL.Path, err, wait)
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// send makes a single attempt, bounded by the timeout.
+func (t *retryTransport) send(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The body is read after RoundTrip returns, so the
+	// timeout is only released once it is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// backoff returns how long to wait before the next attempt,
+// honoring a Retry-After header sent with the response.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, t.retry.MaxBackoff)
+		}
+	}
+
+	d := min(t.retry.MinBackoff<<attempt, t.retry.MaxBackoff)
+	if d <= 0 {
+		return 0
+	}
+
+	// Equal jitter keeps at least half the delay while spreading
+	// out clients that failed at the same time.
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryable reports whether a failed attempt is worth retrying.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// A refused connection never reached Guacamole. A deadline
+		// is only retried when it is the timeout of the attempt
+		// rather than that of the whole request.
+		return errors.Is(err, syscall.ECONNREFUSED) || idempotent(req.Method) &&
+			(errors.Is(err, syscall.ECONNRESET) ||
+				errors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// With Retry-After, the request was turned away unhandled.
+		return idempotent(req.Method) || resp.Header.Get("Retry-After") != ""
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	default:
+		return false
+	}
+}
+
+// idempotent reports whether a request may be sent again after
+// a failure that may have happened once it was applied.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// rewind resets the body of a request so it can be sent again,
+// reporting false when the body cannot be replayed.
+func rewind(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+
+	return true
+}
+
+// drain discards the rest of a response so its connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// cancelBody releases the timeout of a request once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the timeout.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -155,6 +155,16 @@ func Initialize(configDir, logLevelStr, logName string) error {
 	return nil
 }
 
+// Initialized reports whether Initialize set up the global
+// logger, which must happen before any message is logged.
+//
+// **Returns:**
+//
+// bool: Whether the global logger is ready for use.
+func Initialized() bool {
+	return logger != nil && logger.Logger != nil
+}
+
 // Info logs an informational message using the global logger.
 //
 // **Parameters:**
@@ -0,0 +1,452 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package manifest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/techBeck03/guacamole-api-client/types"
+)
+
+// Action is the operation needed to reconcile an object.
+type Action string
+
+const (
+	// ActionCreate creates an object missing from Guacamole.
+	ActionCreate Action = "create"
+	// ActionUpdate updates an object that differs from the manifest.
+	ActionUpdate Action = "update"
+	// ActionDelete deletes an object missing from the manifest.
+	ActionDelete Action = "delete"
+)
+
+// Kind is the type of Guacamole object a Change applies to.
+type Kind string
+
+const (
+	// KindConnectionGroup identifies connection groups.
+	KindConnectionGroup Kind = "connection group"
+	// KindConnection identifies connections.
+	KindConnection Kind = "connection"
+	// KindSharingProfile identifies sharing profiles.
+	KindSharingProfile Kind = "sharing profile"
+	// KindUserGroup identifies user groups.
+	KindUserGroup Kind = "user group"
+	// KindUser identifies users.
+	KindUser Kind = "user"
+)
+
+// Change is a single operation needed to make
+// a Guacamole instance match a manifest.
+//
+// **Attributes:**
+//
+// Action: The operation to perform.
+// Kind: The type of object to operate on.
+// Name: The path or name of the object.
+// Fields: The fields that differ between the manifest and Guacamole.
+type Change struct {
+	Action Action
+	Kind   Kind
+	Name   string
+	Fields []FieldChange
+}
+
+// FieldChange is a single field that differs
+// between the manifest and Guacamole.
+//
+// **Attributes:**
+//
+// Field: The dotted name of the field, such as parameters.hostname.
+// Old: The current value in Guacamole.
+// New: The value from the manifest.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff computes the changes needed to make the current
+// state match the desired state. Changes are ordered so that
+// every object is created after the objects it depends on.
+//
+// **Parameters:**
+//
+// desired: The manifest describing the desired state.
+// current: The manifest describing the current state of Guacamole.
+//
+// **Returns:**
+//
+// []Change: The changes to apply, empty when there is no drift.
+func Diff(desired, current *Manifest) []Change {
+	var changes []Change
+
+	groups := append([]ConnectionGroup(nil), desired.ConnectionGroups...)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return pathLess(groups[i].Path(), groups[j].Path())
+	})
+	for _, g := range groups {
+		cur, found := current.FindConnectionGroup(g.Path())
+		changes = appendChange(changes, KindConnectionGroup, g.Path(), found, diffConnectionGroup(g, cur))
+	}
+
+	conns := append([]Connection(nil), desired.Connections...)
+	sort.SliceStable(conns, func(i, j int) bool {
+		return pathLess(conns[i].Path(), conns[j].Path())
+	})
+	for _, c := range conns {
+		cur, found := current.FindConnection(c.Path())
+		changes = appendChange(changes, KindConnection, c.Path(), found, diffConnection(c, cur))
+	}
+
+	profiles := append([]SharingProfile(nil), desired.SharingProfiles...)
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Path() < profiles[j].Path()
+	})
+	for _, p := range profiles {
+		cur, found := current.FindSharingProfile(p.Path())
+		changes = appendChange(changes, KindSharingProfile, p.Path(), found, diffSharingProfile(p, cur))
+	}
+
+	userGroups := append([]UserGroup(nil), desired.UserGroups...)
+	sort.SliceStable(userGroups, func(i, j int) bool {
+		return userGroups[i].Name < userGroups[j].Name
+	})
+	for _, g := range userGroups {
+		cur, found := current.FindUserGroup(g.Name)
+		changes = appendChange(changes, KindUserGroup, g.Name, found, diffUserGroup(g, cur))
+	}
+
+	users := append([]User(nil), desired.Users...)
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	for _, u := range users {
+		cur, found := current.FindUser(u.Username)
+		changes = appendChange(changes, KindUser, u.Username, found, diffUser(u, cur, found))
+	}
+
+	return changes
+}
+
+// PruneScope limits which objects missing from a manifest
+// may be deleted, so objects owned by others are never touched.
+//
+// **Attributes:**
+//
+// ConnectionGroups: Paths of the connection groups whose descendants
+// may be pruned. ROOT allows pruning every connection and group.
+// Selector: Attributes a user or user group must have to be pruned.
+// Protected: Usernames that are never pruned, such as the authenticated user.
+type PruneScope struct {
+	ConnectionGroups []string
+	Selector         map[string]string
+	Protected        []string
+}
+
+// Prune computes the deletions needed to remove objects
+// within the scope that are not in the desired state.
+// Sharing profiles are deleted before their connections,
+// connections before groups, and child groups before their parents.
+// Deleting a group deletes its contents, so the groups above a
+// desired object are never pruned, even when the manifest does
+// not list them, and neither are the connections of desired
+// sharing profiles.
+//
+// **Parameters:**
+//
+// desired: The manifest describing the desired state.
+// current: The manifest describing the current state of Guacamole.
+// scope: The objects that may be deleted.
+//
+// **Returns:**
+//
+// []Change: The deletions to apply.
+func Prune(desired, current *Manifest, scope PruneScope) []Change {
+	var changes []Change
+
+	kept := keptPaths(desired)
+
+	var profiles []string
+	for _, p := range current.SharingProfiles {
+		if _, found := desired.FindSharingProfile(p.Path()); !found && scope.inGroups(p.Connection) {
+			profiles = append(profiles, p.Path())
+		}
+	}
+	sort.Strings(profiles)
+	changes = appendDeletes(changes, KindSharingProfile, profiles)
+
+	var conns []string
+	for _, c := range current.Connections {
+		if _, found := desired.FindConnection(c.Path()); !found && !kept[c.Path()] && scope.inGroups(c.Path()) {
+			conns = append(conns, c.Path())
+		}
+	}
+	sort.Strings(conns)
+	changes = appendDeletes(changes, KindConnection, conns)
+
+	var groups []string
+	for _, g := range current.ConnectionGroups {
+		if _, found := desired.FindConnectionGroup(g.Path()); !found && !kept[g.Path()] && scope.inGroups(g.Path()) {
+			groups = append(groups, g.Path())
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return pathLess(groups[j], groups[i])
+	})
+	changes = appendDeletes(changes, KindConnectionGroup, groups)
+
+	var users []string
+	for _, u := range current.Users {
+		if _, found := desired.FindUser(u.Username); !found && scope.selects(u.Attributes) &&
+			!types.ArrayContains(&scope.Protected, u.Username) {
+			users = append(users, u.Username)
+		}
+	}
+	sort.Strings(users)
+	changes = appendDeletes(changes, KindUser, users)
+
+	var userGroups []string
+	for _, g := range current.UserGroups {
+		if _, found := desired.FindUserGroup(g.Name); !found && scope.selects(g.Attributes) {
+			userGroups = append(userGroups, g.Name)
+		}
+	}
+	sort.Strings(userGroups)
+	changes = appendDeletes(changes, KindUserGroup, userGroups)
+
+	return changes
+}
+
+// keptPaths returns the paths of the groups holding a desired
+// object and of the connections shared by a desired sharing profile,
+// which must survive a prune for the desired objects to survive it.
+func keptPaths(desired *Manifest) map[string]bool {
+	kept := map[string]bool{}
+	for _, g := range desired.ConnectionGroups {
+		keepAncestors(kept, g.Path())
+	}
+	for _, c := range desired.Connections {
+		keepAncestors(kept, c.Path())
+	}
+	for _, p := range desired.SharingProfiles {
+		kept[p.Connection] = true
+		keepAncestors(kept, p.Path())
+	}
+
+	return kept
+}
+
+// keepAncestors marks every group above a path as kept.
+func keepAncestors(kept map[string]bool, path string) {
+	for i := strings.LastIndex(path, "/"); i > 0; i = strings.LastIndex(path[:i], "/") {
+		kept[path[:i]] = true
+	}
+}
+
+// inGroups reports whether a path is a descendant
+// of one of the scoped connection groups.
+func (s PruneScope) inGroups(path string) bool {
+	for _, group := range s.ConnectionGroups {
+		if group == guacamole.RootGroup || strings.HasPrefix(path, group+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selects reports whether the attributes match
+// every entry of a non-empty selector.
+func (s PruneScope) selects(attributes map[string]string) bool {
+	if len(s.Selector) == 0 {
+		return false
+	}
+
+	for k, v := range s.Selector {
+		if attributes[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func appendDeletes(changes []Change, kind Kind, names []string) []Change {
+	for _, name := range names {
+		changes = append(changes, Change{Action: ActionDelete, Kind: kind, Name: name})
+	}
+
+	return changes
+}
+
+func appendChange(changes []Change, kind Kind, name string, found bool, fields []FieldChange) []Change {
+	switch {
+	case !found:
+		return append(changes, Change{Action: ActionCreate, Kind: kind, Name: name, Fields: fields})
+	case len(fields) > 0:
+		return append(changes, Change{Action: ActionUpdate, Kind: kind, Name: name, Fields: fields})
+	default:
+		return changes
+	}
+}
+
+// pathLess orders parents before their children.
+func pathLess(a, b string) bool {
+	da, db := strings.Count(a, "/"), strings.Count(b, "/")
+	if da != db {
+		return da < db
+	}
+
+	return a < b
+}
+
+func diffConnectionGroup(desired, current ConnectionGroup) []FieldChange {
+	var fields []FieldChange
+
+	fields = diffValue(fields, "type", groupType(current.Type), groupType(desired.Type))
+	fields = diffMap(fields, "attributes", current.Attributes, desired.Attributes, false)
+
+	return fields
+}
+
+// groupType defaults an unset connection group type
+// to the type Guacamole uses when none is given.
+func groupType(t string) string {
+	if t == "" {
+		return defaultGroupType
+	}
+
+	return t
+}
+
+func diffConnection(desired, current Connection) []FieldChange {
+	var fields []FieldChange
+
+	fields = diffValue(fields, "protocol", current.Protocol, desired.Protocol)
+	fields = diffMap(fields, "parameters", current.Parameters, desired.Parameters, true)
+	fields = diffMap(fields, "attributes", current.Attributes, desired.Attributes, false)
+
+	return fields
+}
+
+func diffSharingProfile(desired, current SharingProfile) []FieldChange {
+	var fields []FieldChange
+
+	fields = diffMap(fields, "parameters", current.Parameters, desired.Parameters, true)
+	fields = diffMap(fields, "attributes", current.Attributes, desired.Attributes, false)
+
+	return fields
+}
+
+func diffUserGroup(desired, current UserGroup) []FieldChange {
+	var fields []FieldChange
+
+	fields = diffMap(fields, "attributes", current.Attributes, desired.Attributes, false)
+	fields = diffPermissions(fields, current.Permissions, desired.Permissions)
+
+	return fields
+}
+
+func diffUser(desired, current User, found bool) []FieldChange {
+	var fields []FieldChange
+
+	// Passwords cannot be read back from Guacamole,
+	// so they are only set when the user is created.
+	if !found {
+		fields = diffValue(fields, "password", "", desired.Password)
+	}
+	fields = diffMap(fields, "attributes", current.Attributes, desired.Attributes, false)
+	if desired.Groups != nil {
+		fields = diffList(fields, "groups", current.Groups, desired.Groups)
+	}
+	fields = diffPermissions(fields, current.Permissions, desired.Permissions)
+
+	return fields
+}
+
+func diffPermissions(fields []FieldChange, current, desired *Permissions) []FieldChange {
+	if desired == nil {
+		return fields
+	}
+	if current == nil {
+		current = &Permissions{}
+	}
+
+	fields = diffList(fields, "permissions.system", current.System, desired.System)
+	fields = diffList(fields, "permissions.connections", current.Connections, desired.Connections)
+	fields = diffList(fields, "permissions.connection_groups", current.ConnectionGroups, desired.ConnectionGroups)
+	fields = diffList(fields, "permissions.sharing_profiles", current.SharingProfiles, desired.SharingProfiles)
+
+	return fields
+}
+
+func diffValue(fields []FieldChange, field, current, desired string) []FieldChange {
+	if current == desired {
+		return fields
+	}
+
+	return append(fields, FieldChange{Field: field, Old: current, New: desired})
+}
+
+// diffMap compares the keys set in the manifest. When authoritative
+// is true, keys only present in Guacamole are reported as removed,
+// except secrets: exports may leave them out, so only setting one
+// to an empty value removes it.
+func diffMap(fields []FieldChange, prefix string, current, desired map[string]string, authoritative bool) []FieldChange {
+	keys := map[string]bool{}
+	for k := range desired {
+		keys[k] = true
+	}
+	if authoritative {
+		for k := range current {
+			if !IsSensitive(k) {
+				keys[k] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		fields = diffValue(fields, prefix+"."+k, current[k], desired[k])
+	}
+
+	return fields
+}
+
+func diffList(fields []FieldChange, field string, current, desired []string) []FieldChange {
+	return diffValue(fields, field, joinSorted(current), joinSorted(desired))
+}
+
+func joinSorted(list []string) string {
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+
+	return strings.Join(sorted, ", ")
+}
@@ -0,0 +1,233 @@
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDiff(t *testing.T) {
+	desired, err := manifest.Parse([]byte(labManifest))
+	require.NoError(t, err)
+
+	t.Run("Empty instance", func(t *testing.T) {
+		changes := manifest.Diff(desired, &manifest.Manifest{})
+
+		var names []string
+		for _, change := range changes {
+			require.Equal(t, manifest.ActionCreate, change.Action)
+			names = append(names, change.Name)
+		}
+		require.Equal(t, []string{"lab", "lab/windows", "lab/windows/dc01",
+			"lab/windows/dc01/view-only", "lab-users", "alice"}, names)
+	})
+
+	t.Run("No drift", func(t *testing.T) {
+		current := *desired
+		require.Empty(t, manifest.Diff(desired, &current))
+	})
+
+	t.Run("Drifted connection and user", func(t *testing.T) {
+		current := &manifest.Manifest{
+			ConnectionGroups: desired.ConnectionGroups,
+			SharingProfiles:  desired.SharingProfiles,
+			UserGroups:       desired.UserGroups,
+			Connections: []manifest.Connection{{
+				Name:     "dc01",
+				Group:    "lab/windows",
+				Protocol: "rdp",
+				Parameters: map[string]string{
+					"hostname":    "10.0.0.11",
+					"port":        "3389",
+					"security":    "nla",
+					"ignore-cert": "true",
+				},
+				Attributes: map[string]string{"max-connections": "2"},
+			}},
+			Users: []manifest.User{{
+				Username: "alice",
+				Groups:   []string{"lab-users"},
+				Permissions: &manifest.Permissions{
+					System:      []string{"CREATE_CONNECTION", "CREATE_USER"},
+					Connections: []string{"lab/windows/dc01"},
+				},
+			}},
+		}
+
+		changes := manifest.Diff(desired, current)
+		require.Equal(t, []manifest.Change{
+			{
+				Action: manifest.ActionUpdate,
+				Kind:   manifest.KindConnection,
+				Name:   "lab/windows/dc01",
+				Fields: []manifest.FieldChange{
+					{Field: "parameters.hostname", Old: "10.0.0.11", New: "10.0.0.10"},
+					{Field: "parameters.ignore-cert", Old: "true", New: ""},
+				},
+			},
+			{
+				Action: manifest.ActionUpdate,
+				Kind:   manifest.KindUser,
+				Name:   "alice",
+				Fields: []manifest.FieldChange{
+					{Field: "permissions.system", Old: "CREATE_CONNECTION, CREATE_USER", New: "CREATE_CONNECTION"},
+				},
+			},
+		}, changes)
+	})
+}
+
+func TestPrune(t *testing.T) {
+	desired := &manifest.Manifest{
+		ConnectionGroups: []manifest.ConnectionGroup{{Name: "lab"}},
+		Connections:      []manifest.Connection{{Name: "dc01", Group: "lab", Protocol: "rdp"}},
+		Users:            []manifest.User{{Username: "alice"}},
+	}
+
+	current := &manifest.Manifest{
+		ConnectionGroups: []manifest.ConnectionGroup{
+			{Name: "lab"},
+			{Name: "old", Parent: "lab"},
+			{Name: "nested", Parent: "lab/old"},
+			{Name: "other-team"},
+		},
+		Connections: []manifest.Connection{
+			{Name: "dc01", Group: "lab", Protocol: "rdp"},
+			{Name: "dc02", Group: "lab/old", Protocol: "rdp"},
+			{Name: "db01", Group: "other-team", Protocol: "ssh"},
+		},
+		SharingProfiles: []manifest.SharingProfile{
+			{Name: "watch", Connection: "lab/old/dc02"},
+		},
+		Users: []manifest.User{
+			{Username: "alice", Attributes: map[string]string{"guac-organizational-role": "lab"}},
+			{Username: "bob", Attributes: map[string]string{"guac-organizational-role": "lab"}},
+			{Username: "carol", Attributes: map[string]string{"guac-organizational-role": "dba"}},
+			{Username: "guacadmin", Attributes: map[string]string{"guac-organizational-role": "lab"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		scope    manifest.PruneScope
+		expected []string
+	}{
+		{
+			name:     "Connection group subtree",
+			scope:    manifest.PruneScope{ConnectionGroups: []string{"lab"}},
+			expected: []string{"sharing profile lab/old/dc02/watch", "connection lab/old/dc02", "connection group lab/old/nested", "connection group lab/old"},
+		},
+		{
+			name: "Attribute selector",
+			scope: manifest.PruneScope{
+				Selector:  map[string]string{"guac-organizational-role": "lab"},
+				Protected: []string{"guacadmin"},
+			},
+			expected: []string{"user bob"},
+		},
+		{
+			name:     "Empty scope",
+			scope:    manifest.PruneScope{},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, change := range manifest.Prune(desired, current, tc.scope) {
+				require.Equal(t, manifest.ActionDelete, change.Action)
+				got = append(got, string(change.Kind)+" "+change.Name)
+			}
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestPruneKeepsParentsOfDesiredObjects(t *testing.T) {
+	// The manifest relies on groups and a connection that already
+	// exist in Guacamole without declaring them.
+	desired := &manifest.Manifest{
+		ConnectionGroups: []manifest.ConnectionGroup{{Name: "web", Parent: "lab/prod"}},
+		Connections:      []manifest.Connection{{Name: "dc01", Group: "lab/legacy", Protocol: "rdp"}},
+		SharingProfiles:  []manifest.SharingProfile{{Name: "watch", Connection: "lab/shared/db01"}},
+	}
+
+	current := &manifest.Manifest{
+		ConnectionGroups: []manifest.ConnectionGroup{
+			{Name: "lab"},
+			{Name: "legacy", Parent: "lab"},
+			{Name: "prod", Parent: "lab"},
+			{Name: "web", Parent: "lab/prod"},
+			{Name: "shared", Parent: "lab"},
+			{Name: "old", Parent: "lab"},
+		},
+		Connections: []manifest.Connection{
+			{Name: "dc01", Group: "lab/legacy", Protocol: "rdp"},
+			{Name: "dc02", Group: "lab/legacy", Protocol: "rdp"},
+			{Name: "db01", Group: "lab/shared", Protocol: "ssh"},
+		},
+		SharingProfiles: []manifest.SharingProfile{
+			{Name: "watch", Connection: "lab/shared/db01"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		groups   []string
+		expected []string
+	}{
+		{
+			name:     "ROOT",
+			groups:   []string{guacamole.RootGroup},
+			expected: []string{"connection lab/legacy/dc02", "connection group lab/old"},
+		},
+		{
+			name:     "Ancestor of the undeclared groups",
+			groups:   []string{"lab"},
+			expected: []string{"connection lab/legacy/dc02", "connection group lab/old"},
+		},
+		{
+			name:     "Undeclared group",
+			groups:   []string{"lab/legacy"},
+			expected: []string{"connection lab/legacy/dc02"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := manifest.Diff(desired, current)
+			require.Empty(t, changes)
+
+			var got []string
+			for _, change := range manifest.Prune(desired, current, manifest.PruneScope{ConnectionGroups: tc.groups}) {
+				require.Equal(t, manifest.ActionDelete, change.Action)
+				got = append(got, string(change.Kind)+" "+change.Name)
+			}
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestDiffOmittedSecrets(t *testing.T) {
+	current, err := manifest.Parse([]byte(labManifest))
+	require.NoError(t, err)
+	current.Connections[0].Parameters["password"] = "hunter2"
+	current.Connections[0].Parameters["private-key"] = "KEY"
+
+	data, err := yaml.Marshal(current)
+	require.NoError(t, err)
+	exported, err := manifest.Parse(data)
+	require.NoError(t, err)
+	exported.OmitSecrets()
+
+	require.Empty(t, manifest.Diff(exported, current))
+
+	exported.Connections[0].Parameters["password"] = ""
+	require.Equal(t, []manifest.FieldChange{
+		{Field: "parameters.password", Old: "hunter2", New: ""},
+	}, manifest.Diff(exported, current)[0].Fields)
+}
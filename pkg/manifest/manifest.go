@@ -0,0 +1,818 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/techBeck03/guacamole-api-client/types"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultGroupType = "ORGANIZATIONAL"
+
+// Manifest describes the desired state of a Guacamole instance.
+//
+// **Attributes:**
+//
+// ConnectionGroups: The connection groups to manage.
+// Connections: The connections to manage.
+// SharingProfiles: The sharing profiles to manage.
+// UserGroups: The user groups to manage.
+// Users: The users to manage.
+type Manifest struct {
+	ConnectionGroups []ConnectionGroup `yaml:"connection_groups,omitempty" json:"connection_groups,omitempty"`
+	Connections      []Connection      `yaml:"connections,omitempty" json:"connections,omitempty"`
+	SharingProfiles  []SharingProfile  `yaml:"sharing_profiles,omitempty" json:"sharing_profiles,omitempty"`
+	UserGroups       []UserGroup       `yaml:"user_groups,omitempty" json:"user_groups,omitempty"`
+	Users            []User            `yaml:"users,omitempty" json:"users,omitempty"`
+}
+
+// ConnectionGroup describes a Guacamole connection group.
+//
+// **Attributes:**
+//
+// Name: The name of the group.
+// Parent: The path of the parent group, empty for ROOT.
+// Type: The type of the group (ORGANIZATIONAL or BALANCING).
+// Attributes: Guacamole attributes of the group, such as max-connections.
+type ConnectionGroup struct {
+	Name       string            `yaml:"name" json:"name"`
+	Parent     string            `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Type       string            `yaml:"type,omitempty" json:"type,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// Connection describes a Guacamole connection.
+//
+// **Attributes:**
+//
+// Name: The name of the connection.
+// Group: The path of the connection group holding the connection, empty for ROOT.
+// Protocol: The protocol of the connection (vnc, rdp, ssh, telnet or kubernetes).
+// Parameters: Guacamole connection parameters, such as hostname and port.
+// Attributes: Guacamole attributes of the connection, such as max-connections.
+type Connection struct {
+	Name       string            `yaml:"name" json:"name"`
+	Group      string            `yaml:"group,omitempty" json:"group,omitempty"`
+	Protocol   string            `yaml:"protocol" json:"protocol"`
+	Parameters map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// SharingProfile describes a Guacamole sharing profile,
+// which lets the users of a connection share their session.
+//
+// **Attributes:**
+//
+// Name: The name of the sharing profile.
+// Connection: The path of the connection being shared.
+// Parameters: Guacamole sharing parameters, such as read-only.
+// Attributes: Guacamole attributes of the sharing profile.
+type SharingProfile struct {
+	Name       string            `yaml:"name" json:"name"`
+	Connection string            `yaml:"connection" json:"connection"`
+	Parameters map[string]string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// UserGroup describes a Guacamole user group.
+//
+// **Attributes:**
+//
+// Name: The identifier of the group.
+// Attributes: Guacamole attributes of the group, such as disabled.
+// Permissions: The permissions granted to the group, left untouched when nil.
+type UserGroup struct {
+	Name        string            `yaml:"name" json:"name"`
+	Attributes  map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	Permissions *Permissions      `yaml:"permissions,omitempty" json:"permissions,omitempty"`
+}
+
+// User describes a Guacamole user.
+//
+// **Attributes:**
+//
+// Username: The name of the user.
+// Password: The password set when the user is created.
+// Attributes: Guacamole attributes of the user, such as guac-full-name.
+// Groups: The user groups the user belongs to, left untouched when nil.
+// Permissions: The permissions granted to the user, left untouched when nil.
+type User struct {
+	Username    string            `yaml:"username" json:"username"`
+	Password    string            `yaml:"password,omitempty" json:"password,omitempty"`
+	Attributes  map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	Groups      []string          `yaml:"groups,omitempty" json:"groups,omitempty"`
+	Permissions *Permissions      `yaml:"permissions,omitempty" json:"permissions,omitempty"`
+}
+
+// Permissions describes the permissions granted to a user or user group.
+//
+// **Attributes:**
+//
+// System: System permissions, such as ADMINISTER or CREATE_CONNECTION.
+// Connections: Paths of the connections the holder can use.
+// ConnectionGroups: Paths of the connection groups the holder can use.
+// SharingProfiles: Paths of the sharing profiles the holder can use.
+type Permissions struct {
+	System           []string `yaml:"system,omitempty" json:"system,omitempty"`
+	Connections      []string `yaml:"connections,omitempty" json:"connections,omitempty"`
+	ConnectionGroups []string `yaml:"connection_groups,omitempty" json:"connection_groups,omitempty"`
+	SharingProfiles  []string `yaml:"sharing_profiles,omitempty" json:"sharing_profiles,omitempty"`
+}
+
+// Load reads and validates a manifest from a YAML or JSON file.
+//
+// **Parameters:**
+//
+// path: The path to the manifest file.
+//
+// **Returns:**
+//
+// *Manifest: The parsed manifest.
+//
+// error: An error if the manifest cannot be read or is invalid.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// Parse decodes and validates a manifest. Since JSON
+// is a subset of YAML, both formats are accepted.
+//
+// **Parameters:**
+//
+// data: The encoded manifest.
+//
+// **Returns:**
+//
+// *Manifest: The parsed manifest.
+//
+// error: An error if the manifest cannot be decoded or is invalid.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Validate checks the manifest for missing fields, duplicate
+// objects and values Guacamole would reject.
+//
+// **Returns:**
+//
+// error: An error describing every problem found, if any.
+func (m *Manifest) Validate() error {
+	var errs []error
+
+	groups := map[string]bool{}
+	for _, g := range m.ConnectionGroups {
+		if g.Name == "" {
+			errs = append(errs, errors.New("connection group without a name"))
+			continue
+		}
+		if groups[g.Path()] {
+			errs = append(errs, fmt.Errorf("duplicate connection group %s", g.Path()))
+		}
+		groups[g.Path()] = true
+		if g.Type != "" && !types.StrSlice(types.GuacConnectionGroup{}.ValidTypes()).Has(g.Type) {
+			errs = append(errs, fmt.Errorf("connection group %s has invalid type %q", g.Path(), g.Type))
+		}
+		if _, err := g.GuacConnectionGroup(); err != nil {
+			errs = append(errs, fmt.Errorf("connection group %s: %w", g.Path(), err))
+		}
+	}
+
+	conns := map[string]bool{}
+	for _, c := range m.Connections {
+		if c.Name == "" {
+			errs = append(errs, errors.New("connection without a name"))
+			continue
+		}
+		if conns[c.Path()] {
+			errs = append(errs, fmt.Errorf("duplicate connection %s", c.Path()))
+		}
+		conns[c.Path()] = true
+		if c.Protocol == "" {
+			errs = append(errs, fmt.Errorf("connection %s has no protocol", c.Path()))
+		}
+		if _, err := c.GuacConnection(); err != nil {
+			errs = append(errs, fmt.Errorf("connection %s: %w", c.Path(), err))
+		}
+	}
+
+	errs = append(errs, m.validateSharingProfiles()...)
+
+	userGroups := map[string]bool{}
+	for _, g := range m.UserGroups {
+		if g.Name == "" {
+			errs = append(errs, errors.New("user group without a name"))
+			continue
+		}
+		if userGroups[g.Name] {
+			errs = append(errs, fmt.Errorf("duplicate user group %s", g.Name))
+		}
+		userGroups[g.Name] = true
+		if _, err := g.GuacUserGroup(); err != nil {
+			errs = append(errs, fmt.Errorf("user group %s: %w", g.Name, err))
+		}
+		errs = append(errs, g.Permissions.validate("user group "+g.Name)...)
+	}
+
+	users := map[string]bool{}
+	for _, u := range m.Users {
+		if u.Username == "" {
+			errs = append(errs, errors.New("user without a username"))
+			continue
+		}
+		if users[u.Username] {
+			errs = append(errs, fmt.Errorf("duplicate user %s", u.Username))
+		}
+		users[u.Username] = true
+		if _, err := u.GuacUser(); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", u.Username, err))
+		}
+		errs = append(errs, u.Permissions.validate("user "+u.Username)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manifest) validateSharingProfiles() []error {
+	var errs []error
+
+	profiles := map[string]bool{}
+	for _, p := range m.SharingProfiles {
+		if p.Name == "" || p.Connection == "" {
+			errs = append(errs, errors.New("sharing profile without a name or connection"))
+			continue
+		}
+		if profiles[p.Path()] {
+			errs = append(errs, fmt.Errorf("duplicate sharing profile %s", p.Path()))
+		}
+		profiles[p.Path()] = true
+	}
+
+	return errs
+}
+
+func (p *Permissions) validate(holder string) []error {
+	if p == nil {
+		return nil
+	}
+
+	var errs []error
+	valid := types.StrSlice(types.SystemPermissions{}.ValidChoices())
+	for _, perm := range p.System {
+		if !valid.Has(perm) {
+			errs = append(errs, fmt.Errorf("%s has invalid system permission %q, must be one of %v",
+				holder, perm, valid))
+		}
+	}
+
+	return errs
+}
+
+// FindConnectionGroup looks up a connection group by path.
+//
+// **Parameters:**
+//
+// path: The path of the connection group.
+//
+// **Returns:**
+//
+// ConnectionGroup: The connection group, if found.
+//
+// bool: Whether the connection group was found.
+func (m *Manifest) FindConnectionGroup(path string) (ConnectionGroup, bool) {
+	for _, g := range m.ConnectionGroups {
+		if g.Path() == path {
+			return g, true
+		}
+	}
+
+	return ConnectionGroup{}, false
+}
+
+// FindConnection looks up a connection by path.
+//
+// **Parameters:**
+//
+// path: The path of the connection.
+//
+// **Returns:**
+//
+// Connection: The connection, if found.
+//
+// bool: Whether the connection was found.
+func (m *Manifest) FindConnection(path string) (Connection, bool) {
+	for _, c := range m.Connections {
+		if c.Path() == path {
+			return c, true
+		}
+	}
+
+	return Connection{}, false
+}
+
+// FindSharingProfile looks up a sharing profile by path.
+//
+// **Parameters:**
+//
+// path: The path of the sharing profile.
+//
+// **Returns:**
+//
+// SharingProfile: The sharing profile, if found.
+//
+// bool: Whether the sharing profile was found.
+func (m *Manifest) FindSharingProfile(path string) (SharingProfile, bool) {
+	for _, p := range m.SharingProfiles {
+		if p.Path() == path {
+			return p, true
+		}
+	}
+
+	return SharingProfile{}, false
+}
+
+// FindUserGroup looks up a user group by name.
+//
+// **Parameters:**
+//
+// name: The name of the user group.
+//
+// **Returns:**
+//
+// UserGroup: The user group, if found.
+//
+// bool: Whether the user group was found.
+func (m *Manifest) FindUserGroup(name string) (UserGroup, bool) {
+	for _, g := range m.UserGroups {
+		if g.Name == name {
+			return g, true
+		}
+	}
+
+	return UserGroup{}, false
+}
+
+// FindUser looks up a user by username.
+//
+// **Parameters:**
+//
+// username: The name of the user.
+//
+// **Returns:**
+//
+// User: The user, if found.
+//
+// bool: Whether the user was found.
+func (m *Manifest) FindUser(username string) (User, bool) {
+	for _, u := range m.Users {
+		if u.Username == username {
+			return u, true
+		}
+	}
+
+	return User{}, false
+}
+
+// Path returns the slash separated path of the group.
+func (g ConnectionGroup) Path() string {
+	return JoinPath(g.Parent, g.Name)
+}
+
+// Path returns the slash separated path of the connection.
+func (c Connection) Path() string {
+	return JoinPath(c.Group, c.Name)
+}
+
+// Path returns the slash separated path of the sharing
+// profile, below the path of the connection it shares.
+func (p SharingProfile) Path() string {
+	return JoinPath(p.Connection, p.Name)
+}
+
+// JoinPath joins a parent group path and a name, the
+// same way the Guacamole API client builds connection paths.
+//
+// **Parameters:**
+//
+// parent: The path of the parent group, empty for ROOT.
+// name: The name of the child object.
+//
+// **Returns:**
+//
+// string: The path of the child object.
+func JoinPath(parent, name string) string {
+	if parent == "" || parent == guacamole.RootGroup {
+		return name
+	}
+
+	return parent + "/" + name
+}
+
+// GuacConnectionGroup converts the group into the type
+// used by the Guacamole API client. The parent identifier
+// is left for the caller to resolve.
+//
+// **Returns:**
+//
+// types.GuacConnectionGroup: The Guacamole connection group.
+//
+// error: An error if an attribute is unknown.
+func (g ConnectionGroup) GuacConnectionGroup() (types.GuacConnectionGroup, error) {
+	group := types.GuacConnectionGroup{
+		Name: g.Name,
+		Type: groupType(g.Type),
+	}
+
+	if err := fromStringMap(g.Attributes, &group.Attributes); err != nil {
+		return group, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	return group, nil
+}
+
+// ConnectionGroupFromGuac converts a connection group
+// returned by the Guacamole API client into a manifest ConnectionGroup.
+//
+// **Parameters:**
+//
+// group: The Guacamole connection group.
+// parent: The path of the parent group.
+//
+// **Returns:**
+//
+// ConnectionGroup: The manifest connection group.
+func ConnectionGroupFromGuac(group types.GuacConnectionGroup, parent string) ConnectionGroup {
+	return ConnectionGroup{
+		Name:       group.Name,
+		Parent:     parent,
+		Type:       group.Type,
+		Attributes: toStringMap(group.Attributes),
+	}
+}
+
+// GuacConnection converts the connection into the type
+// used by the Guacamole API client. The parent identifier
+// is left for the caller to resolve.
+//
+// **Returns:**
+//
+// types.GuacConnection: The Guacamole connection.
+//
+// error: An error if a parameter or attribute is unknown.
+func (c Connection) GuacConnection() (types.GuacConnection, error) {
+	conn := types.GuacConnection{
+		Name:     c.Name,
+		Protocol: c.Protocol,
+	}
+
+	if err := fromStringMap(c.Parameters, &conn.Parameters); err != nil {
+		return conn, fmt.Errorf("invalid parameters: %w", err)
+	}
+	if err := fromStringMap(c.Attributes, &conn.Attributes); err != nil {
+		return conn, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	return conn, nil
+}
+
+// ConnectionFromGuac converts a connection returned
+// by the Guacamole API client into a manifest Connection.
+//
+// **Parameters:**
+//
+// conn: The Guacamole connection, including its parameters.
+// group: The path of the connection group holding the connection.
+//
+// **Returns:**
+//
+// Connection: The manifest connection.
+func ConnectionFromGuac(conn types.GuacConnection, group string) Connection {
+	return Connection{
+		Name:       conn.Name,
+		Group:      group,
+		Protocol:   conn.Protocol,
+		Parameters: toStringMap(conn.Parameters),
+		Attributes: toStringMap(conn.Attributes),
+	}
+}
+
+// GuacUser converts the user into the type
+// used by the Guacamole API client.
+//
+// **Returns:**
+//
+// types.GuacUser: The Guacamole user.
+//
+// error: An error if an attribute is unknown.
+func (u User) GuacUser() (types.GuacUser, error) {
+	user := types.GuacUser{
+		Username: u.Username,
+		Password: u.Password,
+	}
+
+	if err := fromStringMap(u.Attributes, &user.Attributes); err != nil {
+		return user, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	return user, nil
+}
+
+// UserFromGuac converts a user returned by the
+// Guacamole API client into a manifest User.
+//
+// **Parameters:**
+//
+// user: The Guacamole user.
+// groups: The user groups the user belongs to.
+// perms: The permissions granted to the user.
+//
+// **Returns:**
+//
+// User: The manifest user.
+func UserFromGuac(user types.GuacUser, groups []string, perms *Permissions) User {
+	sort.Strings(groups)
+
+	return User{
+		Username:    user.Username,
+		Attributes:  toStringMap(user.Attributes),
+		Groups:      groups,
+		Permissions: perms,
+	}
+}
+
+// GuacUserGroup converts the user group into the
+// type used by the Guacamole API client.
+//
+// **Returns:**
+//
+// types.GuacUserGroup: The Guacamole user group.
+//
+// error: An error if an attribute is unknown.
+func (g UserGroup) GuacUserGroup() (types.GuacUserGroup, error) {
+	group := types.GuacUserGroup{Identifier: g.Name}
+
+	if err := fromStringMap(g.Attributes, &group.Attributes); err != nil {
+		return group, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	return group, nil
+}
+
+// UserGroupFromGuac converts a user group returned by
+// the Guacamole API client into a manifest UserGroup.
+//
+// **Parameters:**
+//
+// group: The Guacamole user group.
+// perms: The permissions granted to the group.
+//
+// **Returns:**
+//
+// UserGroup: The manifest user group.
+func UserGroupFromGuac(group types.GuacUserGroup, perms *Permissions) UserGroup {
+	return UserGroup{
+		Name:        group.Identifier,
+		Attributes:  toStringMap(group.Attributes),
+		Permissions: perms,
+	}
+}
+
+// PermissionsFromGuac converts permissions returned by the
+// Guacamole API client into manifest Permissions, translating
+// connection and group identifiers into paths. The manifest only
+// describes system permissions and READ permissions on objects
+// with a known path, so every other permission is returned as
+// dropped instead.
+//
+// **Parameters:**
+//
+// data: The Guacamole permission data.
+// connPaths: The path of each connection, keyed by identifier.
+// groupPaths: The path of each connection group, keyed by identifier.
+// sharingPaths: The path of each sharing profile, keyed by identifier.
+//
+// **Returns:**
+//
+// *Permissions: The manifest permissions.
+//
+// []string: The permissions the manifest cannot describe,
+// such as "UPDATE on connection lab/dc01", sorted.
+func PermissionsFromGuac(data types.GuacPermissionData, connPaths, groupPaths, sharingPaths map[string]string) (*Permissions, []string) {
+	var dropped []string
+	perms := &Permissions{System: append([]string(nil), data.SystemPermissions...)}
+	perms.Connections, dropped = readablePaths(dropped, KindConnection, data.ConnectionPermissions, connPaths)
+	perms.ConnectionGroups, dropped = readablePaths(dropped, KindConnectionGroup, data.ConnectionGroupPermissions, groupPaths)
+	perms.SharingProfiles, dropped = readablePaths(dropped, KindSharingProfile, data.SharingProfilePermissions, sharingPaths)
+	dropped = droppedPermissions(dropped, KindUser, data.UserPermissions)
+	dropped = droppedPermissions(dropped, KindUserGroup, data.UserGroupPermissions)
+	sort.Strings(perms.System)
+	sort.Strings(dropped)
+
+	return perms, dropped
+}
+
+// readablePaths returns the paths of the objects with a READ
+// permission, appending every other permission to dropped.
+func readablePaths(dropped []string, kind Kind, perms map[string][]string, paths map[string]string) ([]string, []string) {
+	var ret []string
+	for id, granted := range perms {
+		path, ok := paths[id]
+		if !ok {
+			path = id
+		}
+		for _, perm := range granted {
+			if ok && perm == "READ" {
+				ret = append(ret, path)
+				continue
+			}
+			dropped = append(dropped, fmt.Sprintf("%s on %s %s", perm, kind, path))
+		}
+	}
+	sort.Strings(ret)
+
+	return ret, dropped
+}
+
+// droppedPermissions appends every permission on
+// objects of a kind the manifest cannot grant to dropped.
+func droppedPermissions(dropped []string, kind Kind, perms map[string][]string) []string {
+	for id, granted := range perms {
+		for _, perm := range granted {
+			dropped = append(dropped, fmt.Sprintf("%s on %s %s", perm, kind, id))
+		}
+	}
+
+	return dropped
+}
+
+// OmitSecrets removes user passwords and secret connection
+// and sharing profile parameters from the manifest, so it
+// can be stored or shared without leaking credentials.
+func (m *Manifest) OmitSecrets() {
+	for i := range m.Users {
+		m.Users[i].Password = ""
+	}
+	for _, c := range m.Connections {
+		omitSensitive(c.Parameters)
+	}
+	for _, p := range m.SharingProfiles {
+		omitSensitive(p.Parameters)
+	}
+}
+
+func omitSensitive(params map[string]string) {
+	for k := range params {
+		if IsSensitive(k) {
+			delete(params, k)
+		}
+	}
+}
+
+// MergeAttributes overlays the desired attributes on top of
+// the current ones, so attributes a manifest does not mention
+// are preserved when an object is updated.
+//
+// **Parameters:**
+//
+// current: The attributes currently set in Guacamole.
+// desired: The attributes set in the manifest.
+//
+// **Returns:**
+//
+// map[string]string: The merged attributes.
+func MergeAttributes(current, desired map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// KeepSecrets adds the secret parameters set in Guacamole
+// that the manifest leaves out to the desired parameters, so
+// updating an object from an export made with OmitSecrets
+// does not remove its passwords and keys.
+//
+// **Parameters:**
+//
+// current: The parameters currently set in Guacamole.
+// desired: The parameters set in the manifest.
+//
+// **Returns:**
+//
+// map[string]string: The desired parameters with the
+// missing secrets added.
+func KeepSecrets(current, desired map[string]string) map[string]string {
+	kept := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		if IsSensitive(k) {
+			kept[k] = v
+		}
+	}
+	for k, v := range desired {
+		kept[k] = v
+	}
+
+	return kept
+}
+
+// toStringMap flattens a Guacamole parameter or attribute
+// struct into a map, dropping unset values.
+func toStringMap(v interface{}) map[string]string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+
+	m := map[string]string{}
+	for k, val := range raw {
+		if s, ok := val.(string); ok && s != "" {
+			m[k] = s
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// fromStringMap fills a Guacamole parameter or attribute
+// struct from a map, rejecting keys the struct does not know.
+func fromStringMap(m map[string]string, v interface{}) error {
+	if len(m) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package manifest_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/guacamole"
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/stretchr/testify/require"
+	"github.com/techBeck03/guacamole-api-client/types"
+	"gopkg.in/yaml.v3"
+)
+
+const labManifest = `
+connection_groups:
+  - name: lab
+  - name: windows
+    parent: lab
+    type: BALANCING
+connections:
+  - name: dc01
+    group: lab/windows
+    protocol: rdp
+    parameters:
+      hostname: 10.0.0.10
+      port: 3389
+      security: nla
+sharing_profiles:
+  - name: view-only
+    connection: lab/windows/dc01
+    parameters:
+      read-only: "true"
+user_groups:
+  - name: lab-users
+    permissions:
+      connection_groups: [lab]
+users:
+  - username: alice
+    password: s3cret
+    groups: [lab-users]
+    permissions:
+      system: [CREATE_CONNECTION]
+      connections: [lab/windows/dc01]
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		expectErr bool
+	}{
+		{
+			name:      "Valid manifest",
+			data:      labManifest,
+			expectErr: false,
+		},
+		{
+			name:      "Empty manifest",
+			data:      "",
+			expectErr: false,
+		},
+		{
+			name:      "JSON manifest",
+			data:      `{"users": [{"username": "bob"}]}`,
+			expectErr: false,
+		},
+		{
+			name:      "Unknown field",
+			data:      "connections:\n  - name: a\n    protocol: vnc\n    hostname: 10.0.0.1\n",
+			expectErr: true,
+		},
+		{
+			name:      "Unknown connection parameter",
+			data:      "connections:\n  - name: a\n    protocol: vnc\n    parameters:\n      hostnme: 10.0.0.1\n",
+			expectErr: true,
+		},
+		{
+			name:      "Duplicate connection",
+			data:      "connections:\n  - name: a\n    protocol: vnc\n  - name: a\n    protocol: vnc\n",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid system permission",
+			data:      "users:\n  - username: a\n    permissions:\n      system: [ROOT]\n",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid group type",
+			data:      "connection_groups:\n  - name: a\n    type: FLAT\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manifest.Parse([]byte(tc.data))
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := manifest.Load(filepath.Join(dir, "missing.yaml"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	path := filepath.Join(dir, "lab.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("connections:\n  - name: dc01\n    color: blue\n"), 0600))
+	_, err = manifest.Load(path)
+	var typeErr *yaml.TypeError
+	require.ErrorAs(t, err, &typeErr)
+}
+
+func TestConnectionRoundTrip(t *testing.T) {
+	m, err := manifest.Parse([]byte(labManifest))
+	require.NoError(t, err)
+
+	conn, ok := m.FindConnection("lab/windows/dc01")
+	require.True(t, ok)
+
+	guacConn, err := conn.GuacConnection()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.10", guacConn.Parameters.Hostname)
+	require.Equal(t, "3389", guacConn.Parameters.Port)
+	require.Equal(t, "nla", guacConn.Parameters.Security)
+
+	require.Equal(t, conn, manifest.ConnectionFromGuac(guacConn, "lab/windows"))
+}
+
+func TestPermissionsFromGuac(t *testing.T) {
+	data := types.GuacPermissionData{
+		SystemPermissions: []string{"CREATE_USER", "ADMINISTER"},
+		ConnectionPermissions: map[string][]string{
+			"1": {"READ"},
+			"2": {"UPDATE"},
+			"3": {"READ"},
+		},
+		ConnectionGroupPermissions: map[string][]string{
+			"ROOT": {"READ"},
+		},
+		SharingProfilePermissions: map[string][]string{
+			"4": {"READ", "ADMINISTER"},
+		},
+		UserPermissions: map[string][]string{
+			"alice": {"UPDATE"},
+		},
+		UserGroupPermissions: map[string][]string{
+			"ops": {"READ"},
+		},
+	}
+
+	perms, dropped := manifest.PermissionsFromGuac(data,
+		map[string]string{"1": "lab/dc01", "2": "lab/dc02"},
+		map[string]string{"ROOT": "ROOT"},
+		map[string]string{"4": "lab/dc01/view-only"})
+
+	require.Equal(t, &manifest.Permissions{
+		System:           []string{"ADMINISTER", "CREATE_USER"},
+		Connections:      []string{"lab/dc01"},
+		ConnectionGroups: []string{"ROOT"},
+		SharingProfiles:  []string{"lab/dc01/view-only"},
+	}, perms)
+	require.Equal(t, []string{
+		"ADMINISTER on sharing profile lab/dc01/view-only",
+		"READ on connection 3",
+		"READ on user group ops",
+		"UPDATE on connection lab/dc02",
+		"UPDATE on user alice",
+	}, dropped)
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	m, err := manifest.Parse([]byte(labManifest))
+	require.NoError(t, err)
+
+	data, err := yaml.Marshal(m)
+	require.NoError(t, err)
+
+	parsed, err := manifest.Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, m, parsed)
+}
+
+func TestOmitSecrets(t *testing.T) {
+	m, err := manifest.Parse([]byte(labManifest))
+	require.NoError(t, err)
+	m.Connections[0].Parameters["password"] = "hunter2"
+	m.Connections[0].Parameters["private-key"] = "KEY"
+
+	m.OmitSecrets()
+
+	require.Empty(t, m.Users[0].Password)
+	require.Equal(t, map[string]string{
+		"hostname": "10.0.0.10",
+		"port":     "3389",
+		"security": "nla",
+	}, m.Connections[0].Parameters)
+}
+
+func TestKeepSecrets(t *testing.T) {
+	current := map[string]string{"hostname": "10.0.0.10", "password": "hunter2", "passphrase": "pp"}
+	desired := map[string]string{"hostname": "10.0.0.11", "passphrase": ""}
+
+	require.Equal(t, map[string]string{
+		"hostname":   "10.0.0.11",
+		"password":   "hunter2",
+		"passphrase": "",
+	}, manifest.KeepSecrets(current, desired))
+}
+
+func TestJoinPath(t *testing.T) {
+	require.Equal(t, "dc01", manifest.JoinPath("", "dc01"))
+	require.Equal(t, "dc01", manifest.JoinPath(guacamole.RootGroup, "dc01"))
+	require.Equal(t, "lab/dc01", manifest.JoinPath("lab", "dc01"))
+}
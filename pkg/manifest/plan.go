@@ -0,0 +1,144 @@
+/*
+Copyright © 2024-present, Jayson Grace <jayson.e.grace@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maskedValue replaces sensitive values in rendered plans.
+const maskedValue = "(sensitive)"
+
+// sensitiveFields lists the parameter and user fields
+// holding secrets that must never be printed.
+var sensitiveFields = map[string]bool{
+	"password":         true,
+	"passphrase":       true,
+	"private-key":      true,
+	"client-key":       true,
+	"gateway-password": true,
+	"sftp-password":    true,
+	"sftp-private-key": true,
+	"sftp-passphrase":  true,
+}
+
+// IsSensitive reports whether a field holds a secret,
+// such as parameters.password or parameters.private-key.
+//
+// **Parameters:**
+//
+// field: The dotted name of the field.
+//
+// **Returns:**
+//
+// bool: Whether the value of the field must be masked.
+func IsSensitive(field string) bool {
+	name := field[strings.LastIndex(field, ".")+1:]
+
+	return sensitiveFields[name]
+}
+
+// Masked returns a copy of the field change with
+// secret values replaced.
+//
+// **Returns:**
+//
+// FieldChange: The field change, safe to print.
+func (f FieldChange) Masked() FieldChange {
+	if !IsSensitive(f.Field) {
+		return f
+	}
+
+	if f.Old != "" {
+		f.Old = maskedValue
+	}
+	if f.New != "" {
+		f.New = maskedValue
+	}
+
+	return f
+}
+
+// WritePlan renders the changes in a human readable
+// form with secret values masked.
+//
+// **Parameters:**
+//
+// w: The writer to render the plan to.
+// changes: The changes computed by Diff.
+//
+// **Returns:**
+//
+// error: An error if the plan cannot be written.
+func WritePlan(w io.Writer, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes, Guacamole matches the manifest.")
+		return err
+	}
+
+	counts := map[Action]int{}
+	for _, change := range changes {
+		counts[change.Action]++
+
+		if _, err := fmt.Fprintf(w, "%s %s %s %s\n", actionSymbol(change.Action), change.Action, change.Kind, change.Name); err != nil {
+			return err
+		}
+
+		for _, field := range change.Fields {
+			if err := writeField(w, change.Action, field.Masked()); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+
+	return err
+}
+
+func writeField(w io.Writer, action Action, field FieldChange) error {
+	var err error
+	switch action {
+	case ActionCreate:
+		_, err = fmt.Fprintf(w, "    %s: %q\n", field.Field, field.New)
+	default:
+		_, err = fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, field.Old, field.New)
+	}
+
+	return err
+}
+
+func actionSymbol(action Action) string {
+	switch action {
+	case ActionCreate:
+		return "+"
+	case ActionUpdate:
+		return "~"
+	case ActionDelete:
+		return "-"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,67 @@
+package manifest_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cowdogmoo/guacinator/pkg/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSensitive(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected bool
+	}{
+		{field: "password", expected: true},
+		{field: "parameters.password", expected: true},
+		{field: "parameters.private-key", expected: true},
+		{field: "parameters.gateway-password", expected: true},
+		{field: "parameters.hostname", expected: false},
+		{field: "parameters.ca-cert", expected: false},
+		{field: "permissions.system", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.field, func(t *testing.T) {
+			require.Equal(t, tc.expected, manifest.IsSensitive(tc.field))
+		})
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	changes := []manifest.Change{
+		{
+			Action: manifest.ActionCreate,
+			Kind:   manifest.KindConnection,
+			Name:   "lab/dc01",
+			Fields: []manifest.FieldChange{
+				{Field: "protocol", New: "rdp"},
+				{Field: "parameters.password", New: "hunter2"},
+			},
+		},
+		{
+			Action: manifest.ActionUpdate,
+			Kind:   manifest.KindUser,
+			Name:   "alice",
+			Fields: []manifest.FieldChange{
+				{Field: "permissions.system", Old: "CREATE_USER", New: ""},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, manifest.WritePlan(&buf, changes))
+
+	out := buf.String()
+	require.Contains(t, out, "+ create connection lab/dc01")
+	require.Contains(t, out, `parameters.password: "(sensitive)"`)
+	require.NotContains(t, out, "hunter2")
+	require.Contains(t, out, `~ update user alice`)
+	require.Contains(t, out, `permissions.system: "CREATE_USER" -> ""`)
+	require.Contains(t, out, "Plan: 1 to create, 1 to update, 0 to delete.")
+
+	buf.Reset()
+	require.NoError(t, manifest.WritePlan(&buf, nil))
+	require.Contains(t, buf.String(), "No changes")
+}